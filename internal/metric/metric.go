@@ -77,6 +77,18 @@ func (metrics *Metrics) AddGaugeMetricDataPoint(
 }
 
 func DecorateMetricName(measurement, fieldKey string) string {
+	separator := "_"
+
+	if runtime.GOOS == "windows" {
+		separator = " "
+	}
+
+	return DecorateMetricNameWithSeparator(measurement, fieldKey, separator)
+}
+
+// DecorateMetricNameWithSeparator is DecorateMetricName with the separator joining the measurement and the field
+// independent of the OS.
+func DecorateMetricNameWithSeparator(measurement, fieldKey, separator string) string {
 	if fieldKey == "" {
 		return ""
 	}
@@ -91,11 +103,5 @@ func DecorateMetricName(measurement, fieldKey string) string {
 		return fieldKey
 	}
 
-	separator := "_"
-
-	if runtime.GOOS == "windows" {
-		separator = " "
-	}
-
 	return strings.Join([]string{measurement, fieldKey}, separator)
 }
@@ -106,3 +106,9 @@ func TestAddGaugeMetricDataPoint(t *testing.T) {
 
 	assert.Equal(t, expected, metrics.metrics)
 }
+
+func TestDecorateMetricNameWithSeparator(t *testing.T) {
+	assert.Equal(t, "cpu.usage_idle", DecorateMetricNameWithSeparator("cpu", "usage_idle", "."))
+	assert.Equal(t, "cpu", DecorateMetricNameWithSeparator("cpu", "value", "."))
+	assert.Equal(t, "", DecorateMetricNameWithSeparator("cpu", "", "."))
+}
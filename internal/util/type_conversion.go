@@ -33,7 +33,7 @@ func ToOtelValue(value interface{}) (interface{}, error) {
 	case uint64:
 		return int64(v), nil
 	case float32:
-		return float64(v), nil
+		return ToOtelValue(float64(v))
 	case float64:
 		if math.IsNaN(v) || math.IsInf(v, 0) {
 			return nil, fmt.Errorf("unsupported value: %v", v)
@@ -44,6 +44,7 @@ func TestToOtelValue(t *testing.T) {
 		{input: math.NaN(), want: nil, wantErr: errors.New("unsupported value: NaN")},
 		{input: math.Inf(1), want: nil, wantErr: errors.New("unsupported value: +Inf")},
 		{input: math.Inf(-1), want: nil, wantErr: errors.New("unsupported value: -Inf")},
+		{input: float32(math.Inf(1)), want: nil, wantErr: errors.New("unsupported value: +Inf")},
 		// unsupported types
 		{input: "test", want: nil, wantErr: errors.New("unsupported type: string")},
 	}
@@ -0,0 +1,486 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package exponential
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+)
+
+const (
+	// MaxScale and MinScale are the range of the scales of the buckets, whose base is 2^(2^-scale) as in the OTEL
+	// exponential histograms.
+	MaxScale int32 = 20
+	MinScale int32 = -10
+	// DefaultMaxBuckets is the number of buckets beyond which the resolution of the buckets is halved.
+	DefaultMaxBuckets = 160
+)
+
+// ExponentialDistribution keeps the weights of the values in buckets of exponentially increasing width, so wide
+// ranges of values (e.g. latencies) use a bounded number of buckets. Bucket i holds the values in
+// (base^i, base^(i+1)]. Whenever the buckets would exceed maxBuckets, the scale is lowered, which merges the pairs
+// of adjacent buckets. The stats (i.e. min, max, sum and sample count) are exact.
+type ExponentialDistribution struct {
+	maximum      float64
+	minimum      float64
+	sampleCount  float64
+	sum          float64
+	initialScale int32
+	scale        int32
+	maxBuckets   int
+	zeroCount    float64
+	buckets      map[int32]float64 // from bucket index to the counter (i.e. weight)
+	minIndex     int32
+	maxIndex     int32
+	unit         string
+}
+
+func NewExponentialDistribution() distribution.Distribution {
+	return NewExponentialDistributionWithScale(MaxScale, DefaultMaxBuckets)
+}
+
+// NewExponentialDistributionWithScale creates a distribution whose buckets start with the base 2^(2^-scale) (e.g.
+// 2 for scale 0) and whose resolution is lowered to keep at most maxBuckets buckets. The scale is limited to
+// [MinScale, MaxScale] and maxBuckets is at least 2.
+func NewExponentialDistributionWithScale(scale int32, maxBuckets int) distribution.Distribution {
+	scale = max(MinScale, min(MaxScale, scale))
+	return &ExponentialDistribution{
+		maximum:      0, // negative number is not supported for now, so zero is the min value
+		minimum:      math.MaxFloat64,
+		sampleCount:  0,
+		sum:          0,
+		initialScale: scale,
+		scale:        scale,
+		maxBuckets:   max(2, maxBuckets),
+		buckets:      map[int32]float64{},
+		unit:         "",
+	}
+}
+
+func (expDist *ExponentialDistribution) Maximum() float64 {
+	return expDist.maximum
+}
+
+func (expDist *ExponentialDistribution) Minimum() float64 {
+	return expDist.minimum
+}
+
+func (expDist *ExponentialDistribution) SampleCount() float64 {
+	return expDist.sampleCount
+}
+
+func (expDist *ExponentialDistribution) Sum() float64 {
+	return expDist.sum
+}
+
+// Scale returns the current scale of the buckets, whose base is 2^(2^-scale).
+func (expDist *ExponentialDistribution) Scale() int32 {
+	return expDist.scale
+}
+
+// ValuesAndCounts returns the geometric middle of each bucket, within the min and max, with its weight.
+func (expDist *ExponentialDistribution) ValuesAndCounts() (values []float64, counts []float64) {
+	values = []float64{}
+	counts = []float64{}
+	if expDist.zeroCount > 0 {
+		values = append(values, 0)
+		counts = append(counts, expDist.zeroCount)
+	}
+	for index, counter := range expDist.buckets {
+		values = append(values, expDist.bucketValue(index))
+		counts = append(counts, counter)
+	}
+	return
+}
+
+func (expDist *ExponentialDistribution) Unit() string {
+	return expDist.unit
+}
+
+func (expDist *ExponentialDistribution) Size() int {
+	size := len(expDist.buckets)
+	if expDist.zeroCount > 0 {
+		size++
+	}
+	return size
+}
+
+// weight is 1/samplingRate
+func (expDist *ExponentialDistribution) AddEntryWithUnit(value float64, weight float64, unit string) error {
+	if weight <= 0 {
+		return fmt.Errorf("unsupported weight %v: %w", weight, distribution.ErrUnsupportedWeight)
+	}
+	if !distribution.IsSupportedValue(value, 0, distribution.MaxValue) {
+		return fmt.Errorf("unsupported value %v: %w", value, distribution.ErrUnsupportedValue)
+	}
+	//sample count
+	expDist.sampleCount += weight
+	//sum
+	expDist.sum += value * weight
+	//min
+	if value < expDist.minimum {
+		expDist.minimum = value
+	}
+	//max
+	if value > expDist.maximum {
+		expDist.maximum = value
+	}
+
+	//buckets
+	if value == 0 {
+		expDist.zeroCount += weight
+	} else {
+		index := BucketIndex(value, expDist.scale)
+		expDist.fit(index, index)
+		expDist.addToBucket(BucketIndex(value, expDist.scale), weight)
+	}
+
+	//unit
+	expDist.mergeUnit(unit)
+	return nil
+}
+
+// weight is 1/samplingRate
+func (expDist *ExponentialDistribution) AddEntry(value float64, weight float64) error {
+	return expDist.AddEntryWithUnit(value, weight, "")
+}
+
+func (expDist *ExponentialDistribution) AddDistribution(distribution distribution.Distribution) {
+	expDist.AddDistributionWithWeight(distribution, 1)
+}
+
+func (expDist *ExponentialDistribution) AddDistributionWithWeight(distribution distribution.Distribution, weight float64) {
+	if distribution.SampleCount()*weight > 0 {
+
+		//buckets
+		if fromDistribution, ok := distribution.(*ExponentialDistribution); ok {
+			if len(fromDistribution.buckets) > 0 {
+				// The buckets of both distributions are merged at the lowest of their scales
+				scale := min(expDist.scale, fromDistribution.scale)
+				expDist.downscale(expDist.scale - scale)
+				shift := fromDistribution.scale - scale
+				expDist.fit(fromDistribution.minIndex>>shift, fromDistribution.maxIndex>>shift)
+				shift = fromDistribution.scale - expDist.scale
+				for index, counter := range fromDistribution.buckets {
+					expDist.addToBucket(index>>shift, counter*weight)
+				}
+			}
+			expDist.zeroCount += fromDistribution.zeroCount * weight
+		} else {
+			log.Printf("E! The from distribution type is not compatible with the to distribution type: from distribution type %T, to distribution type %T", expDist, distribution)
+			return
+		}
+
+		//sample count
+		expDist.sampleCount += distribution.SampleCount() * weight
+		//sum
+		expDist.sum += distribution.Sum() * weight
+		//min
+		if distribution.Minimum() < expDist.minimum {
+			expDist.minimum = distribution.Minimum()
+		}
+		//max
+		if distribution.Maximum() > expDist.maximum {
+			expDist.maximum = distribution.Maximum()
+		}
+
+		//unit
+		expDist.mergeUnit(distribution.Unit())
+	} else {
+		log.Printf("D! SampleCount * Weight should be larger than 0: %v, %v", distribution.SampleCount(), weight)
+	}
+}
+
+// Merge folds the buckets of the other distribution into the distribution, lowering the scale of the buckets if
+// needed. Merging an empty distribution is a no-op.
+func (expDist *ExponentialDistribution) Merge(other *ExponentialDistribution) {
+	if other == nil || other.sampleCount <= 0 {
+		return
+	}
+	expDist.AddDistributionWithWeight(other, 1)
+}
+
+// Quantile returns the weighted q-quantile (0..1) of the values, which is the geometric middle of the lowest bucket
+// whose cumulative weight reaches q of the sample count, within the min and max. It returns the minimum for q=0,
+// the maximum for q=1 and NaN when empty.
+func (expDist *ExponentialDistribution) Quantile(q float64) float64 {
+	if expDist.sampleCount <= 0 {
+		return math.NaN()
+	}
+	if q <= 0 {
+		return expDist.minimum
+	}
+	if q >= 1 {
+		return expDist.maximum
+	}
+	target := q * (expDist.zeroCount + expDist.bucketsWeight())
+	cumulative := expDist.zeroCount
+	if cumulative >= target {
+		return 0
+	}
+	indexes := expDist.sortedIndexes()
+	for _, index := range indexes {
+		cumulative += expDist.buckets[index]
+		if cumulative >= target {
+			return expDist.bucketValue(index)
+		}
+	}
+	return expDist.maximum
+}
+
+// Reset empties the distribution so it can be reused across intervals like a new distribution.
+func (expDist *ExponentialDistribution) Reset() {
+	expDist.maximum = 0
+	expDist.minimum = math.MaxFloat64
+	expDist.sampleCount = 0
+	expDist.sum = 0
+	expDist.scale = expDist.initialScale
+	expDist.zeroCount = 0
+	clear(expDist.buckets)
+	expDist.minIndex, expDist.maxIndex = 0, 0
+	expDist.unit = ""
+}
+
+// exponentialDistributionJSON is the serialized form of an ExponentialDistribution.
+type exponentialDistributionJSON struct {
+	Maximum      float64           `json:"max"`
+	Minimum      float64           `json:"min"`
+	SampleCount  float64           `json:"count"`
+	Sum          float64           `json:"sum"`
+	InitialScale int32             `json:"initial_scale"`
+	Scale        int32             `json:"scale"`
+	MaxBuckets   int               `json:"max_buckets"`
+	ZeroCount    float64           `json:"zero_count,omitempty"`
+	Buckets      map[int32]float64 `json:"buckets,omitempty"`
+	Unit         string            `json:"unit,omitempty"`
+}
+
+// MarshalJSON serializes the buckets and stats of the distribution, so it can be restored exactly with
+// UnmarshalJSON.
+func (expDist *ExponentialDistribution) MarshalJSON() ([]byte, error) {
+	return json.Marshal(exponentialDistributionJSON{
+		Maximum:      expDist.maximum,
+		Minimum:      expDist.minimum,
+		SampleCount:  expDist.sampleCount,
+		Sum:          expDist.sum,
+		InitialScale: expDist.initialScale,
+		Scale:        expDist.scale,
+		MaxBuckets:   expDist.maxBuckets,
+		ZeroCount:    expDist.zeroCount,
+		Buckets:      expDist.buckets,
+		Unit:         expDist.unit,
+	})
+}
+
+// UnmarshalJSON replaces the distribution with the one serialized by MarshalJSON.
+func (expDist *ExponentialDistribution) UnmarshalJSON(b []byte) error {
+	var v exponentialDistributionJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Scale < MinScale || v.Scale > MaxScale || v.InitialScale < MinScale || v.InitialScale > MaxScale {
+		return fmt.Errorf("scale out of range [%d, %d]", MinScale, MaxScale)
+	}
+	if v.MaxBuckets < 2 || len(v.Buckets) > v.MaxBuckets {
+		return fmt.Errorf("invalid max buckets (%d) for %d buckets", v.MaxBuckets, len(v.Buckets))
+	}
+	*expDist = ExponentialDistribution{
+		maximum:      v.Maximum,
+		minimum:      v.Minimum,
+		sampleCount:  v.SampleCount,
+		sum:          v.Sum,
+		initialScale: v.InitialScale,
+		scale:        v.Scale,
+		maxBuckets:   v.MaxBuckets,
+		zeroCount:    v.ZeroCount,
+		buckets:      map[int32]float64{},
+		unit:         v.Unit,
+	}
+	for index, weight := range v.Buckets {
+		expDist.addToBucket(index, weight)
+	}
+	return nil
+}
+
+// ConvertToOtel converts the buckets into explicit bounds. The upper bound of each bucket is limited to the max.
+func (ed *ExponentialDistribution) ConvertToOtel(dp pmetric.HistogramDataPoint) {
+	dp.SetMax(ed.maximum)
+	dp.SetMin(ed.minimum)
+	dp.SetCount(uint64(ed.sampleCount))
+	dp.SetSum(ed.sum)
+	bounds := make([]float64, 0, ed.Size())
+	counts := make([]uint64, 0, ed.Size()+1)
+	if ed.zeroCount > 0 {
+		bounds = append(bounds, 0)
+		counts = append(counts, uint64(ed.zeroCount))
+	}
+	for _, index := range ed.sortedIndexes() {
+		bounds = append(bounds, math.Min(upperBound(index, ed.scale), ed.maximum))
+		// Beware of potential loss of precision due to type conversion.
+		counts = append(counts, uint64(ed.buckets[index]))
+	}
+	// The last count is the bucket above the highest bound, which is always empty.
+	counts = append(counts, 0)
+	dp.ExplicitBounds().FromRaw(bounds)
+	dp.BucketCounts().FromRaw(counts)
+}
+
+// ConvertToOtelExponential converts the distribution into an OTEL exponential histogram data point of the same
+// scale and buckets.
+func (ed *ExponentialDistribution) ConvertToOtelExponential(dp pmetric.ExponentialHistogramDataPoint) {
+	dp.SetCount(uint64(ed.sampleCount))
+	dp.SetSum(ed.sum)
+	if ed.sampleCount > 0 {
+		dp.SetMin(ed.minimum)
+		dp.SetMax(ed.maximum)
+	}
+	dp.SetScale(ed.scale)
+	// Beware of potential loss of precision due to type conversion.
+	dp.SetZeroCount(uint64(ed.zeroCount))
+	if len(ed.buckets) == 0 {
+		return
+	}
+	counts := make([]uint64, ed.maxIndex-ed.minIndex+1)
+	for index, counter := range ed.buckets {
+		counts[index-ed.minIndex] = uint64(counter)
+	}
+	dp.Positive().SetOffset(ed.minIndex)
+	dp.Positive().BucketCounts().FromRaw(counts)
+}
+
+// ConvertFromOtel replaces the distribution with the histogram data point, putting the count of each explicit bucket
+// into the exponential bucket of its upper bound. The overflow bucket above the last bound is put into the next
+// exponential bucket.
+func (ed *ExponentialDistribution) ConvertFromOtel(dp pmetric.HistogramDataPoint, unit string) {
+	ed.Reset()
+	ed.maximum = dp.Max()
+	ed.minimum = dp.Min()
+	ed.sampleCount = float64(dp.Count())
+	ed.sum = dp.Sum()
+	ed.unit = unit
+	for i := 0; i < dp.ExplicitBounds().Len() && i < dp.BucketCounts().Len(); i++ {
+		k := dp.ExplicitBounds().At(i)
+		v := float64(dp.BucketCounts().At(i))
+		if v == 0 {
+			continue
+		}
+		if k <= 0 {
+			ed.zeroCount += v
+			continue
+		}
+		index := BucketIndex(k, ed.scale)
+		ed.fit(index, index)
+		ed.addToBucket(BucketIndex(k, ed.scale), v)
+	}
+
+	n := dp.ExplicitBounds().Len()
+	if dp.BucketCounts().Len() <= n || dp.BucketCounts().At(n) == 0 {
+		return
+	}
+	v := float64(dp.BucketCounts().At(n))
+	var index int32
+	switch {
+	case n > 0 && dp.ExplicitBounds().At(n-1) > 0:
+		index = BucketIndex(dp.ExplicitBounds().At(n-1), ed.scale) + 1
+	case dp.Max() > 0:
+		index = BucketIndex(dp.Max(), ed.scale)
+	default:
+		ed.zeroCount += v
+		return
+	}
+	scale := ed.scale
+	ed.fit(index, index)
+	ed.addToBucket(index>>(scale-ed.scale), v)
+}
+
+// fit lowers the scale until the buckets from lo to hi, at the current scale, fit with the existing buckets.
+func (expDist *ExponentialDistribution) fit(lo, hi int32) {
+	if len(expDist.buckets) > 0 {
+		lo, hi = min(lo, expDist.minIndex), max(hi, expDist.maxIndex)
+	}
+	var by int32
+	for int64(hi>>by)-int64(lo>>by) >= int64(expDist.maxBuckets) && expDist.scale-by > MinScale {
+		by++
+	}
+	expDist.downscale(by)
+}
+
+// downscale lowers the scale by the given amount, merging the buckets which end up with the same index.
+func (expDist *ExponentialDistribution) downscale(by int32) {
+	if by <= 0 {
+		return
+	}
+	expDist.scale -= by
+	if len(expDist.buckets) == 0 {
+		return
+	}
+	buckets := make(map[int32]float64, len(expDist.buckets))
+	for index, counter := range expDist.buckets {
+		buckets[index>>by] += counter
+	}
+	expDist.buckets = buckets
+	expDist.minIndex >>= by
+	expDist.maxIndex >>= by
+}
+
+// addToBucket adds the weight to the bucket of the current scale.
+func (expDist *ExponentialDistribution) addToBucket(index int32, weight float64) {
+	if len(expDist.buckets) == 0 {
+		expDist.minIndex, expDist.maxIndex = index, index
+	} else {
+		expDist.minIndex, expDist.maxIndex = min(index, expDist.minIndex), max(index, expDist.maxIndex)
+	}
+	expDist.buckets[index] += weight
+}
+
+// bucketValue returns the geometric middle of the bucket, within the min and max.
+func (expDist *ExponentialDistribution) bucketValue(index int32) float64 {
+	value := math.Exp2(math.Ldexp(float64(index)+0.5, -int(expDist.scale)))
+	return math.Max(expDist.minimum, math.Min(expDist.maximum, value))
+}
+
+// bucketsWeight returns the sum of the weights of the non-zero buckets.
+func (expDist *ExponentialDistribution) bucketsWeight() float64 {
+	var weight float64
+	for _, counter := range expDist.buckets {
+		weight += counter
+	}
+	return weight
+}
+
+// sortedIndexes returns the indexes of the buckets in increasing order.
+func (expDist *ExponentialDistribution) sortedIndexes() []int32 {
+	indexes := make([]int32, 0, len(expDist.buckets))
+	for index := range expDist.buckets {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	return indexes
+}
+
+func (expDist *ExponentialDistribution) mergeUnit(unit string) {
+	if expDist.unit == "" {
+		expDist.unit = unit
+	} else if expDist.unit != unit && unit != "" {
+		log.Printf("D! Multiple units are detected: %s, %s", expDist.unit, unit)
+	}
+}
+
+// BucketIndex returns the index of the bucket holding the positive value at the scale, i.e.
+// ceil(log2(value) * 2^scale) - 1. Bucket i covers (base^i, base^(i+1)] with base = 2^(2^-scale).
+func BucketIndex(value float64, scale int32) int32 {
+	return int32(math.Ceil(math.Ldexp(math.Log2(value), int(scale)))) - 1
+}
+
+// upperBound returns the upper bound of the bucket, i.e. base^(index+1).
+func upperBound(index int32, scale int32) float64 {
+	return math.Exp2(math.Ldexp(float64(index)+1, -int(scale)))
+}
@@ -0,0 +1,252 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package exponential
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+)
+
+func TestExponentialDistribution(t *testing.T) {
+	dist := NewExponentialDistribution()
+
+	assert.NoError(t, dist.AddEntry(20, 1))
+	assert.NoError(t, dist.AddEntry(30, 1))
+	assert.NoError(t, dist.AddEntryWithUnit(50, 1, "Count"))
+	assert.NoError(t, dist.AddEntry(0, 2))
+
+	assert.Equal(t, 100.0, dist.Sum())
+	assert.Equal(t, 5.0, dist.SampleCount())
+	assert.Equal(t, 0.0, dist.Minimum())
+	assert.Equal(t, 50.0, dist.Maximum())
+	assert.Equal(t, "Count", dist.Unit())
+	assert.Equal(t, 4, dist.Size())
+	values, counts := dist.ValuesAndCounts()
+	assert.Equal(t, len(values), len(counts))
+	valuesCountsMap := map[float64]float64{}
+	for i := 0; i < len(values); i++ {
+		valuesCountsMap[math.Round(values[i])] = counts[i]
+	}
+	assert.Equal(t, map[float64]float64{0: 2, 20: 1, 30: 1, 50: 1}, valuesCountsMap)
+
+	assert.ErrorIs(t, dist.AddEntry(-1, 1), distribution.ErrUnsupportedValue)
+	assert.ErrorIs(t, dist.AddEntry(1, 0), distribution.ErrUnsupportedWeight)
+	assert.Equal(t, 5.0, dist.SampleCount())
+}
+
+func TestExponentialDistributionBoundedMemory(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	dist := NewExponentialDistributionWithScale(MaxScale, 100).(*ExponentialDistribution)
+	var sum, count float64
+	minimum, maximum := math.MaxFloat64, 0.0
+	samples := make([]float64, 0, 1000000)
+	for i := 0; i < 1000000; i++ {
+		// from 1e-6 to 1e12
+		value := math.Pow(10, r.Float64()*18-6)
+		weight := float64(1 + r.Intn(3))
+		assert.NoError(t, dist.AddEntry(value, weight))
+		sum += value * weight
+		count += weight
+		minimum, maximum = math.Min(minimum, value), math.Max(maximum, value)
+		if i%100 == 0 {
+			samples = append(samples, value)
+		}
+	}
+
+	assert.LessOrEqual(t, dist.Size(), 100)
+	assert.Greater(t, dist.Size(), 50)
+	assert.Less(t, dist.Scale(), MaxScale)
+	assert.Equal(t, count, dist.SampleCount())
+	assert.InEpsilon(t, sum, dist.Sum(), 1e-12)
+	assert.Equal(t, minimum, dist.Minimum())
+	assert.Equal(t, maximum, dist.Maximum())
+
+	// The quantiles are accurate within the relative width of a bucket
+	sort.Float64s(samples)
+	base := math.Exp2(math.Exp2(-float64(dist.Scale())))
+	for _, q := range []float64{0.1, 0.5, 0.9, 0.99} {
+		want := samples[int(q*float64(len(samples)))]
+		got := dist.Quantile(q)
+		assert.InEpsilon(t, want, got, base-1+0.05, "q=%v", q)
+	}
+}
+
+func TestBucketIndex(t *testing.T) {
+	assert.Equal(t, int32(0), BucketIndex(2, 0))
+	assert.Equal(t, int32(1), BucketIndex(2.5, 0))
+	assert.Equal(t, int32(-1), BucketIndex(1, 0))
+	assert.Equal(t, int32(-1), BucketIndex(0.75, 1))
+	assert.Equal(t, int32(-2), BucketIndex(0.7, 1))
+	assert.Equal(t, int32(3), BucketIndex(4, 1))
+}
+
+func TestExponentialQuantile(t *testing.T) {
+	dist := NewExponentialDistribution().(*ExponentialDistribution)
+	assert.True(t, math.IsNaN(dist.Quantile(0.5)))
+
+	assert.NoError(t, dist.AddEntry(42, 3))
+	for _, q := range []float64{0, 0.01, 0.5, 0.99, 1} {
+		assert.Equal(t, 42.0, dist.Quantile(q))
+	}
+
+	assert.NoError(t, dist.AddEntry(10, 1))
+	assert.NoError(t, dist.AddEntry(90, 1))
+	// The quantiles are accurate within the relative width of a bucket
+	width := math.Exp2(math.Exp2(-float64(dist.Scale()))) - 1
+	assert.Equal(t, 10.0, dist.Quantile(0))
+	assert.InEpsilon(t, 10.0, dist.Quantile(0.2), width)
+	assert.InEpsilon(t, 42.0, dist.Quantile(0.5), width)
+	assert.InEpsilon(t, 90.0, dist.Quantile(0.9), width)
+	assert.Equal(t, 90.0, dist.Quantile(1))
+}
+
+func TestExponentialMerge(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	a := NewExponentialDistributionWithScale(MaxScale, 20).(*ExponentialDistribution)
+	b := NewExponentialDistributionWithScale(4, 50).(*ExponentialDistribution)
+	all := NewExponentialDistributionWithScale(MaxScale, 20).(*ExponentialDistribution)
+	for i, d := range []*ExponentialDistribution{a, b} {
+		for j := 0; j < 1000; j++ {
+			value := math.Pow(10, r.Float64()*3+float64(i))
+			assert.NoError(t, d.AddEntry(value, 2))
+			assert.NoError(t, all.AddEntry(value, 2))
+		}
+	}
+	assert.NoError(t, b.AddEntry(0, 1))
+	assert.NoError(t, all.AddEntry(0, 1))
+
+	ab := NewExponentialDistributionWithScale(MaxScale, 20).(*ExponentialDistribution)
+	ab.Merge(a)
+	ab.Merge(b)
+	ba := NewExponentialDistributionWithScale(MaxScale, 20).(*ExponentialDistribution)
+	ba.Merge(b)
+	ba.Merge(a)
+	for _, merged := range []*ExponentialDistribution{ab, ba} {
+		assert.InEpsilon(t, all.Sum(), merged.Sum(), 1e-12)
+		assert.Equal(t, all.SampleCount(), merged.SampleCount())
+		assert.Equal(t, all.Minimum(), merged.Minimum())
+		assert.Equal(t, all.Maximum(), merged.Maximum())
+		assert.LessOrEqual(t, merged.Size(), 21)
+		assert.Equal(t, all.SampleCount(), merged.zeroCount+merged.bucketsWeight())
+	}
+	assert.Equal(t, ab.buckets, ba.buckets)
+
+	size := a.Size()
+	a.Merge(NewExponentialDistribution().(*ExponentialDistribution))
+	a.Merge(nil)
+	assert.Equal(t, size, a.Size())
+	assert.Equal(t, 2000.0, a.SampleCount())
+}
+
+func TestExponentialReset(t *testing.T) {
+	dist := NewExponentialDistributionWithScale(8, 10).(*ExponentialDistribution)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, dist.AddEntryWithUnit(math.Pow(2, float64(i)), 1, "Count"))
+	}
+	assert.Less(t, dist.Scale(), int32(8))
+
+	dist.Reset()
+	assert.Equal(t, 0.0, dist.SampleCount())
+	assert.Equal(t, NewExponentialDistributionWithScale(8, 10), dist)
+
+	fresh := NewExponentialDistributionWithScale(8, 10)
+	for _, d := range []distribution.Distribution{dist, fresh} {
+		assert.NoError(t, d.AddEntry(20, 3))
+		assert.NoError(t, d.AddEntry(5, 1))
+	}
+	assert.Equal(t, fresh, dist)
+}
+
+func TestExponentialConvertToOtel(t *testing.T) {
+	dist := NewExponentialDistributionWithScale(0, 160)
+	assert.NoError(t, dist.AddEntry(0, 1))
+	assert.NoError(t, dist.AddEntry(3, 2))
+	assert.NoError(t, dist.AddEntry(4, 1))
+	assert.NoError(t, dist.AddEntry(10, 3))
+
+	dp := pmetric.NewHistogramDataPoint()
+	dist.ConvertToOtel(dp)
+	assert.Equal(t, []float64{0, 4, 10}, dp.ExplicitBounds().AsRaw())
+	assert.Equal(t, []uint64{1, 3, 3, 0}, dp.BucketCounts().AsRaw())
+	assert.True(t, slices.IsSorted(dp.ExplicitBounds().AsRaw()))
+	assert.Equal(t, uint64(7), dp.Count())
+	assert.Equal(t, 40.0, dp.Sum())
+
+	roundTrip := NewExponentialDistributionWithScale(0, 160)
+	roundTrip.ConvertFromOtel(dp, "Count")
+	assert.Equal(t, dist.SampleCount(), roundTrip.SampleCount())
+	assert.Equal(t, dist.Sum(), roundTrip.Sum())
+	assert.Equal(t, dist.(*ExponentialDistribution).buckets, roundTrip.(*ExponentialDistribution).buckets)
+	assert.Equal(t, dist.(*ExponentialDistribution).zeroCount, roundTrip.(*ExponentialDistribution).zeroCount)
+
+	exp := pmetric.NewExponentialHistogramDataPoint()
+	dist.(*ExponentialDistribution).ConvertToOtelExponential(exp)
+	assert.Equal(t, int32(0), exp.Scale())
+	assert.Equal(t, uint64(1), exp.ZeroCount())
+	assert.Equal(t, int32(1), exp.Positive().Offset())
+	assert.Equal(t, []uint64{3, 0, 3}, exp.Positive().BucketCounts().AsRaw())
+	assert.Equal(t, 0.0, exp.Min())
+	assert.Equal(t, 10.0, exp.Max())
+}
+
+func TestExponentialConvertFromOtelOverflow(t *testing.T) {
+	dp := pmetric.NewHistogramDataPoint()
+	dp.ExplicitBounds().FromRaw([]float64{0, 1, 10})
+	dp.BucketCounts().FromRaw([]uint64{1, 2, 3, 4})
+	dp.SetCount(10)
+	dp.SetSum(200)
+	dp.SetMin(0)
+	dp.SetMax(50)
+
+	dist := NewExponentialDistributionWithScale(0, 160).(*ExponentialDistribution)
+	// converting twice must not accumulate the buckets of the first conversion
+	dist.ConvertFromOtel(dp, "Count")
+	dist.ConvertFromOtel(dp, "Count")
+	assert.Equal(t, 10.0, dist.SampleCount())
+	assert.Equal(t, 1.0, dist.zeroCount)
+	assert.Equal(t, 10.0, dist.zeroCount+dist.bucketsWeight())
+	assert.Equal(t, 4.0, dist.buckets[BucketIndex(10, 0)+1])
+
+	roundTrip := pmetric.NewHistogramDataPoint()
+	dist.ConvertToOtel(roundTrip)
+	var total uint64
+	for _, count := range roundTrip.BucketCounts().AsRaw() {
+		total += count
+	}
+	assert.Equal(t, roundTrip.Count(), total)
+	assert.Equal(t, uint64(10), total)
+
+	// without bounds, the whole histogram is in the overflow bucket
+	dp.ExplicitBounds().FromRaw(nil)
+	dp.BucketCounts().FromRaw([]uint64{10})
+	dist.ConvertFromOtel(dp, "Count")
+	assert.Equal(t, 0.0, dist.zeroCount)
+	assert.Equal(t, map[int32]float64{BucketIndex(50, 0): 10}, dist.buckets)
+}
+
+func TestExponentialJSON(t *testing.T) {
+	dist := NewExponentialDistributionWithScale(8, 10).(*ExponentialDistribution)
+	assert.NoError(t, dist.AddEntryWithUnit(0, 1, "Milliseconds"))
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, dist.AddEntryWithUnit(math.Pow(1.7, float64(i)), 0.5, "Milliseconds"))
+	}
+
+	b, err := json.Marshal(dist)
+	assert.NoError(t, err)
+	restored := &ExponentialDistribution{}
+	assert.NoError(t, json.Unmarshal(b, restored))
+	assert.Equal(t, dist, restored)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"scale":21,"max_buckets":10}`), restored))
+	assert.Error(t, json.Unmarshal([]byte(`{"scale":0,"max_buckets":1}`), restored))
+}
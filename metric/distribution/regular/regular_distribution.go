@@ -6,13 +6,18 @@ package regular
 import (
 	"fmt"
 	"log"
+	"maps"
 	"math"
+	"sort"
+	"sync"
 
 	"go.opentelemetry.io/collector/pdata/pmetric"
 
 	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
 )
 
+// RegularDistribution keeps the weight of each distinct value. The entries can be added while another goroutine
+// takes a snapshot of the distribution with Clone, but the other methods must not be called concurrently.
 type RegularDistribution struct {
 	maximum     float64
 	minimum     float64
@@ -20,6 +25,8 @@ type RegularDistribution struct {
 	sum         float64
 	buckets     map[float64]float64 // from  value to the counter (i.e. weight)
 	unit        string
+	maxBuckets  int // the buckets are coalesced beyond it, unbounded when 0
+	mutex       sync.Mutex
 }
 
 func NewRegularDistribution() distribution.Distribution {
@@ -33,6 +40,18 @@ func NewRegularDistribution() distribution.Distribution {
 	}
 }
 
+// NewRegularDistributionWithMaxBuckets returns a distribution keeping at most maxBuckets buckets. Once a new value
+// would exceed it, the adjacent buckets are coalesced into the higher one, so the resolution of the values degrades
+// instead of the memory growing. The maximum, minimum, sum and sample count remain exact. The number of buckets is
+// unbounded when maxBuckets is not positive.
+func NewRegularDistributionWithMaxBuckets(maxBuckets int) distribution.Distribution {
+	regularDist := NewRegularDistribution().(*RegularDistribution)
+	if maxBuckets > 0 {
+		regularDist.maxBuckets = maxBuckets
+	}
+	return regularDist
+}
+
 func (regularDist *RegularDistribution) Maximum() float64 {
 	return regularDist.maximum
 }
@@ -59,6 +78,37 @@ func (regularDist *RegularDistribution) ValuesAndCounts() (values []float64, cou
 	return
 }
 
+// Quantile returns the weighted q-quantile (0..1) of the values, which is the lowest value whose cumulative weight
+// reaches q of the sample count. It returns the minimum for q=0, the maximum for q=1 and NaN when empty.
+func (regularDist *RegularDistribution) Quantile(q float64) float64 {
+	if len(regularDist.buckets) == 0 {
+		return math.NaN()
+	}
+	if q <= 0 {
+		return regularDist.minimum
+	}
+	if q >= 1 {
+		return regularDist.maximum
+	}
+	values := make([]float64, 0, len(regularDist.buckets))
+	var total float64
+	for value, counter := range regularDist.buckets {
+		values = append(values, value)
+		total += counter
+	}
+	sort.Float64s(values)
+
+	target := q * total
+	var cumulative float64
+	for _, value := range values {
+		cumulative += regularDist.buckets[value]
+		if cumulative >= target {
+			return value
+		}
+	}
+	return values[len(values)-1]
+}
+
 func (regularDist *RegularDistribution) Unit() string {
 	return regularDist.unit
 }
@@ -69,6 +119,8 @@ func (regularDist *RegularDistribution) Size() int {
 
 // weight is 1/samplingRate
 func (regularDist *RegularDistribution) AddEntryWithUnit(value float64, weight float64, unit string) error {
+	regularDist.mutex.Lock()
+	defer regularDist.mutex.Unlock()
 	if weight <= 0 {
 		return fmt.Errorf("unsupported weight %v: %w", weight, distribution.ErrUnsupportedWeight)
 	}
@@ -90,6 +142,7 @@ func (regularDist *RegularDistribution) AddEntryWithUnit(value float64, weight f
 
 	//values and counts
 	regularDist.buckets[value] += weight
+	regularDist.coalesceBuckets()
 
 	//unit
 	if regularDist.unit == "" {
@@ -110,6 +163,8 @@ func (regularDist *RegularDistribution) AddDistribution(distribution distributio
 }
 
 func (regularDist *RegularDistribution) AddDistributionWithWeight(distribution distribution.Distribution, weight float64) {
+	regularDist.mutex.Lock()
+	defer regularDist.mutex.Unlock()
 	if distribution.SampleCount()*weight > 0 {
 
 		//values and counts
@@ -117,6 +172,7 @@ func (regularDist *RegularDistribution) AddDistributionWithWeight(distribution d
 			for bucketNumber, bucketCounts := range fromDistribution.buckets {
 				regularDist.buckets[bucketNumber] += bucketCounts * weight
 			}
+			regularDist.coalesceBuckets()
 		} else {
 			log.Printf("E! The from distribution type is not compatible with the to distribution type: from distribution type %T, to distribution type %T", regularDist, distribution)
 			return
@@ -146,21 +202,107 @@ func (regularDist *RegularDistribution) AddDistributionWithWeight(distribution d
 	}
 }
 
+// coalesceBuckets reduces the number of buckets to 3/4 of maxBuckets once it is exceeded, so the buckets are not
+// coalesced on each new value. The adjacent buckets with the lowest combined counter are coalesced into the higher
+// one first, so the buckets tend to hold the same weight. Each value remains the upper bound of the values counted
+// in its bucket, and the maximum is kept as a bucket.
+func (regularDist *RegularDistribution) coalesceBuckets() {
+	if regularDist.maxBuckets == 0 || len(regularDist.buckets) <= regularDist.maxBuckets {
+		return
+	}
+	values := make([]float64, 0, len(regularDist.buckets))
+	for value := range regularDist.buckets {
+		values = append(values, value)
+	}
+	sort.Float64s(values)
+	counters := make([]float64, len(values))
+	for i, value := range values {
+		counters[i] = regularDist.buckets[value]
+	}
+
+	target := max(regularDist.maxBuckets-regularDist.maxBuckets/4, 1)
+	for len(values) > target {
+		lowest := 0
+		for i := 1; i < len(values)-1; i++ {
+			if counters[i]+counters[i+1] < counters[lowest]+counters[lowest+1] {
+				lowest = i
+			}
+		}
+		counters[lowest+1] += counters[lowest]
+		delete(regularDist.buckets, values[lowest])
+		values = append(values[:lowest], values[lowest+1:]...)
+		counters = append(counters[:lowest], counters[lowest+1:]...)
+	}
+	for i, value := range values {
+		regularDist.buckets[value] = counters[i]
+	}
+}
+
+// Merge folds the buckets of the other distribution into the distribution, as if its entries were added, without
+// replaying them. Merging an empty distribution is a no-op.
+func (regularDist *RegularDistribution) Merge(other *RegularDistribution) {
+	if other == nil || len(other.buckets) == 0 {
+		return
+	}
+	regularDist.AddDistributionWithWeight(other, 1)
+}
+
+// Clone returns a deep copy of the distribution, which is a consistent snapshot even while entries are added
+// concurrently. The copy is independent of the distribution.
+func (regularDist *RegularDistribution) Clone() *RegularDistribution {
+	regularDist.mutex.Lock()
+	defer regularDist.mutex.Unlock()
+	return &RegularDistribution{
+		maximum:     regularDist.maximum,
+		minimum:     regularDist.minimum,
+		sampleCount: regularDist.sampleCount,
+		sum:         regularDist.sum,
+		buckets:     maps.Clone(regularDist.buckets),
+		unit:        regularDist.unit,
+		maxBuckets:  regularDist.maxBuckets,
+	}
+}
+
+// Reset empties the distribution so it can be reused across intervals like a new distribution, keeping the
+// allocated buckets.
+func (regularDist *RegularDistribution) Reset() {
+	regularDist.mutex.Lock()
+	defer regularDist.mutex.Unlock()
+	regularDist.maximum = 0
+	regularDist.minimum = math.MaxFloat64
+	regularDist.sampleCount = 0
+	regularDist.sum = 0
+	clear(regularDist.buckets)
+	regularDist.unit = ""
+}
+
 func (rd *RegularDistribution) ConvertToOtel(dp pmetric.HistogramDataPoint) {
 	dp.SetMax(rd.maximum)
 	dp.SetMin(rd.minimum)
 	dp.SetCount(uint64(rd.sampleCount))
 	dp.SetSum(rd.sum)
-	dp.ExplicitBounds().EnsureCapacity(len(rd.buckets))
-	dp.BucketCounts().EnsureCapacity(len(rd.buckets))
-	for k, v := range rd.buckets {
-		dp.ExplicitBounds().Append(k)
+	// Each value is the upper bound of its bucket. The bounds must be increasing, and the last count is the
+	// bucket above the highest value, which is always empty.
+	bounds := make([]float64, 0, len(rd.buckets))
+	for k := range rd.buckets {
+		bounds = append(bounds, k)
+	}
+	sort.Float64s(bounds)
+	counts := make([]uint64, len(bounds)+1)
+	for i, k := range bounds {
 		// Beware of potential loss of precision due to type conversion.
-		dp.BucketCounts().Append(uint64(v))
+		counts[i] = uint64(rd.buckets[k])
 	}
+	dp.ExplicitBounds().FromRaw(bounds)
+	dp.BucketCounts().FromRaw(counts)
 }
 
+// ConvertFromOtel sets the distribution from the histogram data point, using the upper bound of each bucket as its
+// value. The overflow bucket above the last bound has no upper bound, so its count is put at the maximum of the data
+// point, or at the last bound when the maximum is unknown.
 func (rd *RegularDistribution) ConvertFromOtel(dp pmetric.HistogramDataPoint, unit string) {
+	rd.mutex.Lock()
+	defer rd.mutex.Unlock()
 	rd.maximum = dp.Max()
 	rd.minimum = dp.Min()
 	rd.sampleCount = float64(dp.Count())
@@ -171,6 +313,25 @@ func (rd *RegularDistribution) ConvertFromOtel(dp pmetric.HistogramDataPoint, un
 		v := dp.BucketCounts().At(i)
 		rd.buckets[k] = float64(v)
 	}
+	if n := dp.ExplicitBounds().Len(); dp.BucketCounts().Len() > n && dp.BucketCounts().At(n) > 0 {
+		rd.buckets[overflowValue(dp)] += float64(dp.BucketCounts().At(n))
+	}
+	rd.coalesceBuckets()
+}
+
+// overflowValue returns the value representing the overflow bucket of the data point, which holds the values above
+// its last bound.
+func overflowValue(dp pmetric.HistogramDataPoint) float64 {
+	n := dp.ExplicitBounds().Len()
+	switch {
+	case dp.HasMax() && (n == 0 || dp.Max() > dp.ExplicitBounds().At(n-1)):
+		return dp.Max()
+	case n > 0:
+		return dp.ExplicitBounds().At(n - 1)
+	case dp.Count() > 0:
+		return dp.Sum() / float64(dp.Count())
+	}
+	return 0
 }
 
 func (regularDist *RegularDistribution) GetCount(value float64) float64 {
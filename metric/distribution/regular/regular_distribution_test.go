@@ -5,9 +5,13 @@ package regular
 
 import (
 	"math"
+	"math/rand"
+	"sort"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pmetric"
 
 	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
 )
@@ -102,3 +106,289 @@ func cloneRegularDistribution(dist *RegularDistribution) *RegularDistribution {
 	}
 	return clonedDist
 }
+
+func TestConvertToOtel(t *testing.T) {
+	dist := NewRegularDistribution()
+	assert.NoError(t, dist.AddEntry(30, 2))
+	assert.NoError(t, dist.AddEntry(10, 1))
+	assert.NoError(t, dist.AddEntry(20, 3))
+
+	dp := pmetric.NewHistogramDataPoint()
+	dist.ConvertToOtel(dp)
+	assert.Equal(t, []float64{10, 20, 30}, dp.ExplicitBounds().AsRaw())
+	assert.Equal(t, []uint64{1, 3, 2, 0}, dp.BucketCounts().AsRaw())
+
+	roundTrip := NewRegularDistribution()
+	roundTrip.ConvertFromOtel(dp, "Count")
+	values, counts := roundTrip.ValuesAndCounts()
+	valuesCountsMap := map[float64]float64{}
+	for i := 0; i < len(values); i++ {
+		valuesCountsMap[values[i]] = counts[i]
+	}
+	assert.Equal(t, map[float64]float64{10: 1, 20: 3, 30: 2}, valuesCountsMap)
+}
+
+func TestConvertFromOtelOverflow(t *testing.T) {
+	// the values 5, 15 x2, 250 x3 and 900 rebucketed into the bounds 10 and 100
+	dp := pmetric.NewHistogramDataPoint()
+	dp.SetCount(7)
+	dp.SetSum(1685)
+	dp.SetMin(5)
+	dp.SetMax(900)
+	dp.ExplicitBounds().FromRaw([]float64{10, 100})
+	dp.BucketCounts().FromRaw([]uint64{1, 2, 4})
+
+	dist := NewRegularDistribution().(*RegularDistribution)
+	dist.ConvertFromOtel(dp, "Count")
+	assert.Equal(t, map[float64]float64{10: 1, 100: 2, 900: 4}, dist.buckets)
+	assert.Equal(t, 7.0, dist.SampleCount())
+	assert.Equal(t, 1685.0, dist.Sum())
+	assert.Equal(t, 900.0, dist.Maximum())
+
+	// the round trip keeps the count of every bucket
+	roundTrip := pmetric.NewHistogramDataPoint()
+	dist.ConvertToOtel(roundTrip)
+	assert.Equal(t, []float64{10, 100, 900}, roundTrip.ExplicitBounds().AsRaw())
+	assert.Equal(t, []uint64{1, 2, 4, 0}, roundTrip.BucketCounts().AsRaw())
+	assert.Equal(t, dp.Count(), roundTrip.Count())
+
+	// the overflow count is put at the last bound when the maximum is unknown
+	dp.RemoveMax()
+	dist = NewRegularDistribution().(*RegularDistribution)
+	dist.ConvertFromOtel(dp, "Count")
+	assert.Equal(t, map[float64]float64{10: 1, 100: 6}, dist.buckets)
+
+	// a histogram with only the overflow bucket
+	dp = pmetric.NewHistogramDataPoint()
+	dp.SetCount(2)
+	dp.SetSum(8)
+	dp.BucketCounts().FromRaw([]uint64{2})
+	dist = NewRegularDistribution().(*RegularDistribution)
+	dist.ConvertFromOtel(dp, "Count")
+	assert.Equal(t, map[float64]float64{4: 2}, dist.buckets)
+}
+
+func TestQuantile(t *testing.T) {
+	dist := NewRegularDistribution().(*RegularDistribution)
+	assert.True(t, math.IsNaN(dist.Quantile(0.5)))
+
+	assert.NoError(t, dist.AddEntry(42, 3))
+	for _, q := range []float64{0, 0.01, 0.5, 0.99, 1} {
+		assert.Equal(t, 42.0, dist.Quantile(q))
+	}
+
+	assert.NoError(t, dist.AddEntry(10, 1))
+	assert.NoError(t, dist.AddEntry(90, 1))
+	assert.Equal(t, 10.0, dist.Quantile(0))
+	assert.Equal(t, 10.0, dist.Quantile(0.2))
+	assert.Equal(t, 42.0, dist.Quantile(0.5))
+	assert.Equal(t, 42.0, dist.Quantile(0.8))
+	assert.Equal(t, 90.0, dist.Quantile(0.81))
+	assert.Equal(t, 90.0, dist.Quantile(1))
+}
+
+func TestQuantileBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		dist := NewRegularDistribution().(*RegularDistribution)
+		// The integer weights are expanded into the individual samples
+		var samples []float64
+		for j := 0; j < 1+r.Intn(50); j++ {
+			value := math.Round(r.Float64()*1000) / 10
+			weight := 1 + r.Intn(5)
+			assert.NoError(t, dist.AddEntry(value, float64(weight)))
+			for k := 0; k < weight; k++ {
+				samples = append(samples, value)
+			}
+		}
+		sort.Float64s(samples)
+
+		for _, q := range []float64{0, 0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.99, 0.999, 1} {
+			rank := int(math.Ceil(q * float64(len(samples))))
+			want := samples[max(rank, 1)-1]
+			assert.Equal(t, want, dist.Quantile(q), "q=%v samples=%v", q, samples)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		a := NewRegularDistribution().(*RegularDistribution)
+		b := NewRegularDistribution().(*RegularDistribution)
+		all := NewRegularDistribution().(*RegularDistribution)
+		for _, d := range []*RegularDistribution{a, b} {
+			for j := 0; j < 1+r.Intn(50); j++ {
+				value := math.Round(r.Float64()*1000) / 10
+				weight := float64(1 + r.Intn(5))
+				assert.NoError(t, d.AddEntry(value, weight))
+				assert.NoError(t, all.AddEntry(value, weight))
+			}
+		}
+		ab := NewRegularDistribution().(*RegularDistribution)
+		ab.Merge(a)
+		ab.Merge(b)
+		ba := NewRegularDistribution().(*RegularDistribution)
+		ba.Merge(b)
+		ba.Merge(a)
+
+		for _, merged := range []*RegularDistribution{ab, ba} {
+			assert.InDelta(t, all.Sum(), merged.Sum(), 1e-9)
+			assert.Equal(t, all.SampleCount(), merged.SampleCount())
+			assert.Equal(t, all.Minimum(), merged.Minimum())
+			assert.Equal(t, all.Maximum(), merged.Maximum())
+			assert.Equal(t, all.buckets, merged.buckets)
+		}
+	}
+}
+
+func TestMergeEmpty(t *testing.T) {
+	dist := NewRegularDistribution().(*RegularDistribution)
+	assert.NoError(t, dist.AddEntryWithUnit(10, 2, "Count"))
+	assert.NoError(t, dist.AddEntry(20, 1))
+
+	dist.Merge(NewRegularDistribution().(*RegularDistribution))
+	dist.Merge(nil)
+	assert.Equal(t, 40.0, dist.Sum())
+	assert.Equal(t, 3.0, dist.SampleCount())
+	assert.Equal(t, 10.0, dist.Minimum())
+	assert.Equal(t, 20.0, dist.Maximum())
+	assert.Equal(t, "Count", dist.Unit())
+	assert.Equal(t, map[float64]float64{10: 2, 20: 1}, dist.buckets)
+}
+
+func TestReset(t *testing.T) {
+	dist := NewRegularDistribution().(*RegularDistribution)
+	assert.NoError(t, dist.AddEntryWithUnit(30, 2, "Count"))
+	assert.NoError(t, dist.AddEntry(10, 1))
+
+	dist.Reset()
+	assert.Equal(t, 0.0, dist.SampleCount())
+	assert.Equal(t, 0, dist.Size())
+	assert.Equal(t, NewRegularDistribution(), dist)
+
+	fresh := NewRegularDistribution()
+	for _, d := range []distribution.Distribution{dist, fresh} {
+		assert.NoError(t, d.AddEntryWithUnit(20, 3, "Seconds"))
+		assert.NoError(t, d.AddEntry(5, 1))
+	}
+	assert.Equal(t, fresh, dist)
+	assert.Equal(t, 5.0, dist.Minimum())
+	assert.Equal(t, 20.0, dist.Maximum())
+	assert.Equal(t, "Seconds", dist.Unit())
+}
+
+func TestClone(t *testing.T) {
+	dist := NewRegularDistribution().(*RegularDistribution)
+	assert.NoError(t, dist.AddEntryWithUnit(10, 2, "Count"))
+	assert.NoError(t, dist.AddEntry(20, 1))
+
+	clone := dist.Clone()
+	assert.NoError(t, dist.AddEntry(30, 1))
+	assert.NoError(t, dist.AddEntry(10, 1))
+	assert.Equal(t, 40.0, clone.Sum())
+	assert.Equal(t, 3.0, clone.SampleCount())
+	assert.Equal(t, 10.0, clone.Minimum())
+	assert.Equal(t, 20.0, clone.Maximum())
+	assert.Equal(t, "Count", clone.Unit())
+	assert.Equal(t, map[float64]float64{10: 2, 20: 1}, clone.buckets)
+
+	assert.NoError(t, clone.AddEntry(5, 1))
+	assert.Equal(t, 5.0, dist.SampleCount())
+	assert.Equal(t, 0.0, dist.GetCount(5))
+}
+
+func TestCloneConcurrently(t *testing.T) {
+	dist := NewRegularDistribution().(*RegularDistribution)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 10000; i++ {
+			assert.NoError(t, dist.AddEntry(float64(i%100+1), 1))
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		clone := dist.Clone()
+		if clone.SampleCount() == 0 {
+			continue
+		}
+		// The stats of the snapshot match its buckets
+		var count, sum float64
+		minimum, maximum := math.MaxFloat64, 0.0
+		for value, weight := range clone.buckets {
+			count += weight
+			sum += value * weight
+			minimum, maximum = math.Min(minimum, value), math.Max(maximum, value)
+		}
+		assert.Equal(t, count, clone.SampleCount())
+		assert.Equal(t, sum, clone.Sum())
+		assert.Equal(t, minimum, clone.Minimum())
+		assert.Equal(t, maximum, clone.Maximum())
+	}
+	wg.Wait()
+	assert.Equal(t, 10000.0, dist.Clone().SampleCount())
+}
+
+func TestMaxBuckets(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	dist := NewRegularDistributionWithMaxBuckets(64).(*RegularDistribution)
+	var sum, count float64
+	minimum, maximum := math.MaxFloat64, 0.0
+	for i := 0; i < 100000; i++ {
+		// distinct microsecond latencies up to 100s
+		value := float64(i*1000 + r.Intn(1000))
+		weight := float64(1 + r.Intn(3))
+		assert.NoError(t, dist.AddEntry(value, weight))
+		assert.LessOrEqual(t, dist.Size(), 64)
+		sum += value * weight
+		count += weight
+		minimum, maximum = math.Min(minimum, value), math.Max(maximum, value)
+	}
+
+	assert.Greater(t, dist.Size(), 32)
+	assert.Equal(t, count, dist.SampleCount())
+	assert.Equal(t, sum, dist.Sum())
+	assert.Equal(t, minimum, dist.Minimum())
+	assert.Equal(t, maximum, dist.Maximum())
+	_, counts := dist.ValuesAndCounts()
+	var bucketsWeight float64
+	for _, c := range counts {
+		bucketsWeight += c
+	}
+	assert.Equal(t, count, bucketsWeight)
+	// The maximum remains a bucket, so the buckets are valid upper bounds
+	assert.Greater(t, dist.GetCount(maximum), 0.0)
+	assert.InEpsilon(t, maximum/2, dist.Quantile(0.5), 0.05)
+
+	// The cap is kept by the clones and across the resets
+	clone := dist.Clone()
+	assert.NoError(t, clone.AddEntry(math.Pi, 1))
+	assert.LessOrEqual(t, clone.Size(), 64)
+	dist.Reset()
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, dist.AddEntry(float64(i), 1))
+	}
+	assert.LessOrEqual(t, dist.Size(), 64)
+
+	unbounded := NewRegularDistributionWithMaxBuckets(0)
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, unbounded.AddEntry(float64(i), 1))
+	}
+	assert.Equal(t, 1000, unbounded.Size())
+}
+
+func TestMaxBucketsAddDistribution(t *testing.T) {
+	dist := NewRegularDistributionWithMaxBuckets(10)
+	other := NewRegularDistribution()
+	for i := 1; i <= 100; i++ {
+		assert.NoError(t, other.AddEntry(float64(i), 1))
+	}
+	dist.AddDistribution(other)
+	assert.LessOrEqual(t, dist.Size(), 10)
+	assert.Equal(t, 100.0, dist.SampleCount())
+	assert.Equal(t, 5050.0, dist.Sum())
+	assert.Equal(t, 1.0, dist.Minimum())
+	assert.Equal(t, 100.0, dist.Maximum())
+}
@@ -4,8 +4,10 @@
 package accumulator
 
 import (
+	"container/list"
 	"context"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,21 +15,97 @@ import (
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/models"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
-
-	"github.com/aws/amazon-cloudwatch-agent/internal/util"
 )
 
 // OtelAccumulator implements the telegraf.Accumulator interface, but works as an OTel plugin by passing the metrics
 // onward to the next consumer
+//
+// The Add methods and AddError can be called concurrently from the goroutines of a service input, while a single
+// consumer calls GetOtelMetrics or DrainBatched. The Set methods change the conversion options and must be called
+// before the accumulator is used.
 type OtelAccumulator interface {
 	// Accumulator Interface https://github.com/influxdata/telegraf/blob/381dc2272390cd9de1ce2b047a953f8337b55647/accumulator.go
 	telegraf.Accumulator
 
 	// GetOtelMetrics return the final OTEL metric that were gathered by scrape controller for each plugin
 	GetOtelMetrics() pmetric.Metrics
+
+	// ClearStickyMetrics stops emitting the last known value of the sticky series of the metric names, or of
+	// every sticky series when no name is given
+	ClearStickyMetrics(names ...string)
+
+	// DrainBatched return the final OTEL metric, like GetOtelMetrics, split into batches of at most maxPerBatch
+	// data points
+	DrainBatched(maxPerBatch int) []pmetric.Metrics
+
+	// SetStringFieldsAsAttributes enables adding the string fields of a metric as attributes of the data points
+	// of its numeric fields instead of dropping them
+	SetStringFieldsAsAttributes(enabled bool)
+
+	// SetHistogramPercentiles sets the percentiles (e.g. 50, 90, 99) of the distributions added as attributes of
+	// their histogram data points
+	SetHistogramPercentiles(percentiles []float64)
+
+	// SetNameSanitizer sets the function building the metric names from the measurement and the field joined with
+	// a space, instead of the OS dependent joining
+	SetNameSanitizer(sanitizer func(string) string)
+
+	// SetNameJoiner sets the separator joining the measurement and the field into the metric name (e.g. "." or "")
+	SetNameJoiner(separator string)
+
+	// SetResourceAttributes sets the static attributes (e.g. deployment environment) added to every resource
+	SetResourceAttributes(attributes map[string]string)
+
+	// SetResourceProvider sets the function returning the attributes added to every new resource, evaluated lazily
+	// so they reflect its latest value
+	SetResourceProvider(provider func() pcommon.Map)
+
+	// SetScope sets the name and version of the instrumentation scope the metrics are emitted under
+	SetScope(name, version string)
+
+	// SetErrorRateLimit logs at most maxPerWindow identical consecutive errors within the window
+	SetErrorRateLimit(window time.Duration, maxPerWindow int)
+
+	// SuppressedErrors returns the number of errors not logged because of the error rate limit
+	SuppressedErrors() int64
+
+	// ClampedValues returns the number of values clamped to the range supported by CloudWatch with ClampValues
+	ClampedValues() int64
+
+	// DroppedOutOfOrder returns the number of points dropped with DropOutOfOrder because they are older than the latest point of their series
+	DroppedOutOfOrder() int64
+
+	// DroppedInvalidHistograms returns the number of distributions dropped because their sum is not finite
+	DroppedInvalidHistograms() int64
+
+	// AttributeKeyCollisions returns the number of tags merged with LowercaseAttributeKeys because their keys only differ by case
+	AttributeKeyCollisions() int64
+
+	// DroppedAttributes returns the number of attributes dropped from the data points over MaxAttributes
+	DroppedAttributes() int64
+
+	// DumpInputs serializes the Telegraf metrics received since the last GetOtelMetrics, before any conversion
+	DumpInputs() []byte
+
+	// ReplayInputs adds the Telegraf metrics serialized by DumpInputs as if they were received again
+	ReplayInputs(b []byte) error
+
+	// SetPrecisionForMetric sets the precision the timestamps of the metric are rounded to, instead of the one from
+	// SetPrecision
+	SetPrecisionForMetric(name string, precision time.Duration)
+
+	// SetCounterMonotonic sets if the sums converted from counters are monotonic
+	SetCounterMonotonic(monotonic bool)
+
+	// SetCounterTemporality sets the aggregation temporality of the sums converted from counters
+	SetCounterTemporality(temporality pmetric.AggregationTemporality)
+
+	// EstimatedCardinality returns the estimated number of distinct series of the metric name
+	EstimatedCardinality(metricName string) uint64
 }
 
 /*
@@ -36,6 +114,8 @@ otelAccumulator struct
 @logger      Zap Logger
 @precision   Round the timestamp during collection
 @metrics     Otel Metrics which stacks multiple metrics through AddCounter, AddGauge, etc before resetting
+@cfg         Conversion options applied when converting Telegraf metrics to Otel metrics
+@stats       Counters of the values modified or dropped by the conversion options
 */
 type otelAccumulator struct {
 	input          *models.RunningInput
@@ -45,12 +125,55 @@ type otelAccumulator struct {
 	logger         *zap.Logger
 	precision      time.Duration
 	metrics        pmetric.Metrics
+	cfg            Config
+	stats          conversionStats
+
+	// mergedHistograms buffers the histograms merged across an attribute until the next GetOtelMetrics
+	mergedHistograms map[string]*mergedHistogram
+	// bucketHistograms buffers the buckets of Prometheus style histograms until the next GetOtelMetrics
+	bucketHistograms map[string]*bucketHistogram
+	// lastTimestamps tracks the latest timestamp of each series to drop out of order points
+	lastTimestamps seriesLRU[time.Time]
+	// resourceCache indexes the accumulated ResourceMetrics by resource fingerprint until the next GetOtelMetrics
+	resourceCache map[string]pmetric.ResourceMetrics
+	// metadata caches the values of the MetadataProvider
+	metadata metadataCache
+	// errorLimit tracks the repeated errors suppressed with ErrorRateLimitWindow
+	errorLimit errorLimiter
+	// cardinality holds the sketches of the series of each metric name with EstimateCardinality
+	cardinality cardinalityEstimator
+	// aggregatedGauges buffers the gauges aggregated with GaugeAggregationByField until the next GetOtelMetrics
+	aggregatedGauges map[string]*aggregatedGauge
+	// reassembledSummaries buffers the quantiles split with ReassembleSummaryByQuantileTag until the next GetOtelMetrics
+	reassembledSummaries map[string]*reassembledSummary
+	// lastCounters holds the last value of each counter series to compute their rates
+	lastCounters seriesLRU[counterSample]
+	// counterStarts holds the start time and last value of each cumulative counter series
+	counterStarts map[string]*list.Element
+	// counterStartOrder orders the counterStarts from the most to the least recently seen series
+	counterStartOrder list.List
+	// seenHistograms holds the identity and stats of the histograms added since the last GetOtelMetrics
+	seenHistograms map[string]struct{}
+	// numericTypes holds the type of the first value of each metric name for StableNumericType
+	numericTypes seriesLRU[pmetric.NumberDataPointValueType]
+	// stickySeries holds the last known value of the series listed in StickyMetricNames until cleared
+	stickySeries map[string]*stickySeries
+	// knownSeries holds the last metric of each measurement and tags to keep them alive with EmitTimestampOnly
+	knownSeries seriesLRU[telegraf.Metric]
+	// recordedInputs holds the metrics received since the last GetOtelMetrics with RecordInputs
+	recordedInputs []recordedMetric
 
 	mutex sync.Mutex
 }
 
 func NewAccumulator(input *models.RunningInput, ctx context.Context, consumer consumer.Metrics, logger *zap.Logger) OtelAccumulator {
+	return NewAccumulatorWithConfig(input, ctx, consumer, logger, Config{})
+}
+
+// NewAccumulatorWithConfig creates an OtelAccumulator which applies the conversion options in cfg.
+func NewAccumulatorWithConfig(input *models.RunningInput, ctx context.Context, consumer consumer.Metrics, logger *zap.Logger, cfg Config) OtelAccumulator {
 	_, isServiceInput := input.Input.(telegraf.ServiceInput)
+	cfg.AttributeExtract = validAttributeExtract(cfg.AttributeExtract, logger)
 	return &otelAccumulator{
 		input:          input,
 		isServiceInput: isServiceInput,
@@ -59,6 +182,7 @@ func NewAccumulator(input *models.RunningInput, ctx context.Context, consumer co
 		logger:         logger,
 		precision:      time.Nanosecond,
 		metrics:        pmetric.NewMetrics(),
+		cfg:            cfg,
 	}
 }
 
@@ -70,14 +194,18 @@ func (o *otelAccumulator) AddCounter(measurement string, fields map[string]inter
 	o.addMetric(measurement, tags, fields, telegraf.Counter, t...)
 }
 
-// AddSummary is only being used by OpenTelemetry and Prometheus. https://github.com/influxdata/telegraf/search?q=AddSummary
-// However, we already have a Prometheus Receiver which uses AddFields so there is actually no use case for AddSummary.
+// AddSummary is used by inputs such as OpenTelemetry, Prometheus and statsd timings. https://github.com/influxdata/telegraf/search?q=AddSummary
+// The summaries are converted into OTEL summaries with their count, sum and quantiles.
 func (o *otelAccumulator) AddSummary(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
-	o.logger.Error("CloudWatchAgent's adapter does not support Telegraf Summary.")
+	o.addMetric(measurement, tags, fields, telegraf.Summary, t...)
 }
 
 func (o *otelAccumulator) AddHistogram(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
-	o.addMetric(measurement, tags, fields, telegraf.Histogram, t...)
+	// The accumulator clock is used instead of the passed time for deterministic timestamps
+	if o.cfg.HistogramUseAccumulatorClock {
+		t = nil
+	}
+	o.addMetric(measurement, tags, snapshotDistributions(fields), telegraf.Histogram, t...)
 }
 
 func (o *otelAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
@@ -85,7 +213,7 @@ func (o *otelAccumulator) AddFields(measurement string, fields map[string]interf
 }
 
 func (o *otelAccumulator) AddMetric(m telegraf.Metric) {
-	m.SetTime(m.Time().Round(o.precision))
+	m.SetTime(m.Time().Round(o.precisionFor(m.Name())))
 	o.convertToOtelMetricsAndAddMetric(m)
 }
 
@@ -93,10 +221,116 @@ func (o *otelAccumulator) SetPrecision(precision time.Duration) {
 	o.precision = precision
 }
 
+// SetPrecisionForMetric sets the precision the timestamps of the metric (i.e. the Telegraf measurement) are rounded
+// to, instead of the one from SetPrecision.
+func (o *otelAccumulator) SetPrecisionForMetric(name string, precision time.Duration) {
+	if o.cfg.PrecisionByMetric == nil {
+		o.cfg.PrecisionByMetric = map[string]time.Duration{}
+	}
+	o.cfg.PrecisionByMetric[name] = precision
+}
+
+// SetStringFieldsAsAttributes enables adding the string fields of a metric as attributes of the data points of its
+// numeric fields instead of dropping them.
+func (o *otelAccumulator) SetStringFieldsAsAttributes(enabled bool) {
+	o.cfg.StringFieldsAsAttributes = enabled
+}
+
+// SetHistogramPercentiles sets the percentiles of the distributions added as attributes of their histogram data
+// points. No attribute is added when empty.
+func (o *otelAccumulator) SetHistogramPercentiles(percentiles []float64) {
+	o.cfg.HistogramPercentiles = percentiles
+}
+
+// SetNameSanitizer sets the function building the metric names from the measurement and the field joined with a
+// space (e.g. SanitizeUnderscore). The names depend on the OS when nil.
+func (o *otelAccumulator) SetNameSanitizer(sanitizer func(string) string) {
+	o.cfg.NameSanitizer = sanitizer
+}
+
+// SetNameJoiner sets the separator joining the measurement and the field into the metric name (e.g. banana.peel
+// with "." or bananapeel with ""). The NameSanitizer is applied to the joined name.
+func (o *otelAccumulator) SetNameJoiner(separator string) {
+	o.cfg.NameJoiner = JoinWithSeparator(separator)
+}
+
+// SetResourceAttributes sets the static attributes added to every resource. They do not overwrite the existing
+// resource attributes.
+func (o *otelAccumulator) SetResourceAttributes(attributes map[string]string) {
+	o.cfg.ResourceAttributes = attributes
+}
+
+// SetResourceProvider sets the function returning the attributes added to every new resource. It is evaluated for
+// each resource, so the attributes known after the agent started (e.g. cluster name) reflect their latest value.
+// The provided attributes do not overwrite the existing resource attributes but take precedence over the static
+// ones.
+func (o *otelAccumulator) SetResourceProvider(provider func() pcommon.Map) {
+	o.cfg.ResourceProvider = provider
+}
+
+// SetScope sets the name and version of the instrumentation scope the metrics are emitted under (e.g. the name of
+// the input), so the collector pipeline can route them by source. MeasurementScope takes precedence for the name.
+func (o *otelAccumulator) SetScope(name, version string) {
+	o.cfg.ScopeName = name
+	o.cfg.ScopeVersion = version
+}
+
+// SetErrorRateLimit logs at most maxPerWindow identical consecutive errors within the window, so a flapping input
+// does not flood the logs. The number of suppressed errors is logged when the error changes, the window ends or on
+// GetOtelMetrics. The errors are not limited when the window is not positive.
+func (o *otelAccumulator) SetErrorRateLimit(window time.Duration, maxPerWindow int) {
+	o.cfg.ErrorRateLimitWindow = window
+	o.cfg.MaxErrorsPerWindow = maxPerWindow
+}
+
+// SuppressedErrors returns the number of errors not logged because of the error rate limit.
+func (o *otelAccumulator) SuppressedErrors() int64 {
+	return o.stats.suppressedErrors.Load()
+}
+
+// ClampedValues returns the number of values clamped to the range supported by CloudWatch with ClampValues.
+func (o *otelAccumulator) ClampedValues() int64 {
+	return o.stats.clampedValues.Load()
+}
+
+// DroppedOutOfOrder returns the number of points dropped with DropOutOfOrder because they are older than the latest point of their series.
+func (o *otelAccumulator) DroppedOutOfOrder() int64 {
+	return o.stats.droppedOutOfOrder.Load()
+}
+
+// DroppedInvalidHistograms returns the number of distributions dropped because their sum is not finite.
+func (o *otelAccumulator) DroppedInvalidHistograms() int64 {
+	return o.stats.droppedInvalidHistograms.Load()
+}
+
+// AttributeKeyCollisions returns the number of tags merged with LowercaseAttributeKeys because their keys only differ by case.
+func (o *otelAccumulator) AttributeKeyCollisions() int64 {
+	return o.stats.attributeKeyCollisions.Load()
+}
+
+// DroppedAttributes returns the number of attributes dropped from the data points over MaxAttributes.
+func (o *otelAccumulator) DroppedAttributes() int64 {
+	return o.stats.droppedAttributes.Load()
+}
+
+// SetCounterMonotonic sets if the sums converted from counters are monotonic. It does not apply to the measurements
+// in CounterMonotonicByMeasurement.
+func (o *otelAccumulator) SetCounterMonotonic(monotonic bool) {
+	o.cfg.NonMonotonicCounters = !monotonic
+}
+
+// SetCounterTemporality sets the aggregation temporality of the sums converted from counters.
+func (o *otelAccumulator) SetCounterTemporality(temporality pmetric.AggregationTemporality) {
+	o.cfg.CounterTemporality = temporality
+}
+
 func (o *otelAccumulator) AddError(err error) {
 	if err == nil {
 		return
 	}
+	if !o.allowError(err.Error()) {
+		return
+	}
 
 	o.logger.Error("Error with adapter", zap.Error(err))
 }
@@ -110,13 +344,26 @@ func (o *otelAccumulator) addMetric(
 	metricType telegraf.ValueType,
 	t ...time.Time,
 ) {
-	m := metric.New(measurement, tags, fields, o.getTime(t), metricType)
+	m := metric.New(measurement, tags, fields, o.getTime(measurement, t), metricType)
 	o.convertToOtelMetricsAndAddMetric(m)
 }
 
 // convertToOtelMetricsAndAddMetric converts Telegraf's Metric model to OTEL Stream Model
 // and add the OTEl Metric to channel
 func (o *otelAccumulator) convertToOtelMetricsAndAddMetric(m telegraf.Metric) {
+	o.recordInput(m)
+	if m = o.transform(m); m == nil {
+		return
+	}
+	if len(m.Fields()) == 0 && o.cfg.EmitTimestampOnly {
+		o.addTimestampOnlyMetric(m)
+		return
+	}
+
+	var seriesKey string
+	if o.cfg.EmitTimestampOnly {
+		seriesKey = timestampOnlyKey(m)
+	}
 	mMetric, err := o.modifyMetricAndConvertToOtelValue(m)
 	if err != nil {
 		o.logger.Warn(
@@ -133,7 +380,7 @@ func (o *otelAccumulator) convertToOtelMetricsAndAddMetric(m telegraf.Metric) {
 		return
 	}
 
-	oMetric, err := ConvertTelegrafToOtelMetrics(mMetric.Name(), mMetric.Fields(), mMetric.Tags(), mMetric.Type(), mMetric.Time())
+	oMetric, err := o.convertToOtelMetrics(mMetric, mMetric.Time())
 	if err != nil {
 		o.logger.Warn("Convert to Otel Metric failed",
 			zap.Any("name", oMetric),
@@ -143,24 +390,93 @@ func (o *otelAccumulator) convertToOtelMetricsAndAddMetric(m telegraf.Metric) {
 			zap.Error(err))
 		return
 	}
+	o.stampCounterStartTimes(oMetric)
+	if o.cfg.EmitTimestampOnly {
+		o.rememberSeries(seriesKey, mMetric)
+	}
+	o.rememberStickySeries(mMetric)
+	o.addOtelMetrics(oMetric)
+	o.addCounterRates(mMetric)
+}
+
+// convertToOtelMetrics converts the fields of the metric into OTEL metrics at the timestamp. The fields with a suffix
+// configured in FieldSuffixToAttribute are converted with the suffix as an attribute of their base metric.
+func (o *otelAccumulator) convertToOtelMetrics(m telegraf.Metric, timestamp time.Time) (pmetric.Metrics, error) {
+	groups := o.splitFieldSuffixes(m)
+	oMetric := pmetric.NewMetrics()
+	for _, group := range groups {
+		converted, err := convertTelegrafToOtelMetrics(&o.cfg, m.Name(), group.fields, group.tags, m.Type(), timestamp)
+		if err != nil {
+			return oMetric, err
+		}
+		if len(groups) == 1 {
+			oMetric = converted
+			break
+		}
+		converted.ResourceMetrics().MoveAndAppendTo(oMetric.ResourceMetrics())
+	}
+	o.stampDataPointIndex(oMetric)
+	return oMetric, nil
+}
+
+// addOtelMetrics processes the converted OTEL metrics and either consumes them for service inputs or
+// accumulates them until the next GetOtelMetrics.
+func (o *otelAccumulator) addOtelMetrics(oMetric pmetric.Metrics) {
+	if err := o.refreshInstanceMetadata(); err != nil {
+		o.AddError(err)
+	}
+	o.processOtelMetrics(oMetric)
+	if oMetric.ResourceMetrics().Len() == 0 {
+		return
+	}
 
 	// Gather and Start can add metrics concurrently. Therefore, a mutex ensures thread-safe access to the resource metrics
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 	if o.isServiceInput {
+		// The metrics of service inputs are consumed without going through GetOtelMetrics
+		o.estimateCardinality(oMetric)
 		err := o.consumer.ConsumeMetrics(o.ctx, oMetric)
 		if err != nil {
 			o.AddError(err)
 		}
 	} else {
-		oMetric.ResourceMetrics().MoveAndAppendTo(o.metrics.ResourceMetrics())
+		o.appendResourceMetrics(oMetric)
 	}
 }
 
-// GetOtelMetrics return the final OTEL metric that were gathered by scrape controller for each plugin
+// GetOtelMetrics return the final OTEL metric that were gathered by scrape controller for each plugin.
+// It is safe to call concurrently with the Add methods: the accumulated metrics are swapped under the mutex, so
+// every data point is returned by exactly one GetOtelMetrics.
 func (o *otelAccumulator) GetOtelMetrics() pmetric.Metrics {
+	// The metadata is refreshed before the mutex is held, since the flushed metrics only read the cached metadata
+	if err := o.refreshInstanceMetadata(); err != nil {
+		o.AddError(err)
+	}
+	o.mutex.Lock()
+	o.flushMergedHistograms()
+	o.flushBucketHistograms()
+	o.flushAggregatedGauges()
+	o.flushReassembledSummaries()
+	o.flushStickySeries()
+	o.appendHeartbeat()
+	o.resourceCache = nil
+	o.seenHistograms = nil
+	o.recordedInputs = nil
 	finalMetrics := o.metrics
 	o.metrics = pmetric.NewMetrics()
+	o.mutex.Unlock()
+
+	o.flushSuppressedErrors()
+	o.resolveCollisions(finalMetrics)
+	o.hoistCommonAttributes(finalMetrics)
+	// The series are counted on the final metrics, which include the ones built on flush, before the scrape id
+	// makes every series distinct
+	o.estimateCardinality(finalMetrics)
+	o.stampScrapeID(finalMetrics)
+	if o.cfg.OnAfterDrain != nil {
+		o.cfg.OnAfterDrain(finalMetrics.DataPointCount())
+	}
 	return finalMetrics
 }
 
@@ -168,7 +484,7 @@ func (o *otelAccumulator) GetOtelMetrics() pmetric.Metrics {
 // and convert to value supported by OTEL (int64 and float64).
 // Distributions are not modified yet.
 func (o *otelAccumulator) modifyMetricAndConvertToOtelValue(m telegraf.Metric) (telegraf.Metric, error) {
-	if len(m.Fields()) == 0 {
+	if len(m.FieldList()) == 0 {
 		return nil, nil
 	}
 
@@ -179,43 +495,135 @@ func (o *otelAccumulator) modifyMetricAndConvertToOtelValue(m telegraf.Metric) (
 		return nil, nil
 	}
 
+	// Metrics without a measurement fall back to the name of the input, unless configured to be dropped
+	if mMetric.Name() == "" {
+		if o.cfg.DropUnnamedMeasurements {
+			return nil, nil
+		}
+		mMetric.SetName(o.input.Config.Name)
+	}
+
+	mMetric = o.resolveType(mMetric)
+
+	o.modifyTags(mMetric)
+	if o.cfg.DropUntaggedMetrics && len(mMetric.TagList()) == 0 {
+		return nil, nil
+	}
+
+	o.applyTimestampField(mMetric)
+	o.applyTimestampOffset(mMetric)
+	o.dropOutOfOrder(mMetric)
+	o.dropDisallowedFields(mMetric)
+	if len(mMetric.FieldList()) == 0 {
+		return nil, nil
+	}
+
 	if m.Type() == telegraf.Histogram {
+		o.dropInvalidHistograms(mMetric)
+		o.dropDuplicateHistograms(mMetric)
+		if err := o.collectHistogramBuckets(mMetric); err != nil {
+			o.AddError(err)
+		}
+		o.mergeHistograms(mMetric)
+		if len(mMetric.FieldList()) == 0 {
+			return nil, nil
+		}
 		return mMetric, nil
 	}
 	// Otel only supports numeric data. Therefore, filter unsupported data type and convert metrics value to corresponding value before
 	// converting the data model
 	// https://github.com/open-telemetry/opentelemetry-collector/blob/bdc3e22d28006b6c9496568bd8d8bcf0aa1e4950/pdata/pmetric/metrics.go#L106-L113
 	var errs error
-	for field, value := range mMetric.Fields() {
+	var dropped int
+	// The fields are removed and replaced while converting them, so they are iterated over a pooled copy
+	buf := getConversionBuffer()
+	defer putConversionBuffer(buf)
+	buf.fields = append(buf.fields, mMetric.FieldList()...)
+	for _, f := range buf.fields {
+		field, value := f.Key, f.Value
 		// Convert all int,uint to int64 and float to float64 and bool to int.
-		otelValue, err := util.ToOtelValue(value)
+		otelValue, err := o.toOtelValue(mMetric, field, value)
+		if str, ok := value.(string); ok && otelValue == nil && o.cfg.StringFieldsAsAttributes {
+			mMetric.RemoveField(field)
+			if str != "" {
+				buf.stringAttributes[field] = str
+			}
+			continue
+		}
 		if err != nil {
 			errs = multierr.Append(errs, fmt.Errorf("field (%q): %w", field, err))
 		}
+		otelValue = o.stabilizeNumericType(mMetric, field, otelValue)
+		otelValue = o.forceIntType(mMetric, field, otelValue)
+		if err := o.validateField(mMetric, field, otelValue); err != nil {
+			o.AddError(err)
+			mMetric.RemoveField(field)
+			continue
+		}
 
 		if otelValue == nil {
 			mMetric.RemoveField(field)
+			dropped++
+		} else if o.isDroppedZeroField(field, otelValue) {
+			mMetric.RemoveField(field)
+		} else if _, ok := value.(bool); ok && o.cfg.BoolFieldSuffix != "" {
+			mMetric.RemoveField(field)
+			mMetric.AddField(field+o.cfg.BoolFieldSuffix, otelValue)
 		} else if value != otelValue {
 			mMetric.AddField(field, otelValue)
 		}
 	}
 
-	if len(mMetric.Fields()) == 0 {
+	if len(mMetric.FieldList()) == 0 {
 		return nil, fmt.Errorf("empty metrics after converting fields: %w", errs)
 	}
+	// The tags take precedence over the string fields of the same name. The string fields are hashed like the
+	// tags, since they are added after modifyTags.
+	for field, value := range buf.stringAttributes {
+		if !mMetric.HasTag(field) {
+			mMetric.AddTag(field, o.hashAttributeValue(field, value))
+		}
+	}
+	if o.cfg.EmitPerMetricDropCount && dropped > 0 {
+		mMetric.AddTag(droppedFieldCountAttribute, strconv.Itoa(dropped))
+	}
+	o.addFieldTotal(mMetric)
+
+	o.aggregateGauges(mMetric)
+	o.reassembleSummaries(mMetric)
+	if len(mMetric.FieldList()) == 0 {
+		return nil, nil
+	}
 
 	return mMetric, nil
 }
 
+// now returns the current time from the configured clock.
+func (o *otelAccumulator) now() time.Time {
+	if o.cfg.Clock != nil {
+		return o.cfg.Clock()
+	}
+	return time.Now()
+}
+
 // Adapted from https://github.com/influxdata/telegraf/blob/b526945c64a56450b836656a6a2002b8bf748b78/agent/accumulator.go#L112
-func (o *otelAccumulator) getTime(t []time.Time) time.Time {
+func (o *otelAccumulator) getTime(measurement string, t []time.Time) time.Time {
 	var timestamp time.Time
 	if len(t) > 0 {
 		timestamp = t[0]
 	} else {
-		timestamp = time.Now()
+		timestamp = o.now()
+	}
+	return timestamp.Round(o.precisionFor(measurement))
+}
+
+// precisionFor returns the precision the timestamps of the measurement are rounded to, which is the one from
+// PrecisionByMetric if any.
+func (o *otelAccumulator) precisionFor(measurement string) time.Duration {
+	if precision, ok := o.cfg.PrecisionByMetric[measurement]; ok {
+		return precision
 	}
-	return timestamp.Round(o.precision)
+	return o.precision
 }
 
 // TrackingAccumulator is an Accumulator that provides a signal when the
@@ -8,6 +8,10 @@ import (
 	"math"
 	"math/rand"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -18,7 +22,10 @@ import (
 	"go.opentelemetry.io/collector/consumer/consumertest"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 
+	"github.com/aws/amazon-cloudwatch-agent/internal/metric"
 	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/regular"
 )
 
@@ -168,6 +175,161 @@ func TestAddHistogram(t *testing.T) {
 	as.Equal(dist.Maximum(), dp.Max())
 	as.Equal(dist.Sum(), dp.Sum())
 	as.Equal(dist.SampleCount(), float64(dp.Count()))
+	as.Equal(dp.ExplicitBounds().Len()+1, dp.BucketCounts().Len())
+	as.True(slices.IsSorted(dp.ExplicitBounds().AsRaw()))
+}
+
+func TestNameSanitizer(t *testing.T) {
+	testCases := map[string]struct {
+		sanitizer func(string) string
+		want      []string
+	}{
+		"Underscore": {
+			sanitizer: SanitizeUnderscore,
+			want:      []string{"banana_peel", "banana_weight", "banana"},
+		},
+		"PreserveSpaces": {
+			sanitizer: SanitizePreserveSpaces,
+			want:      []string{"banana peel", "banana weight", "banana"},
+		},
+		"Custom": {
+			sanitizer: func(name string) string { return strings.ToUpper(strings.ReplaceAll(name, " ", ".")) },
+			want:      []string{"BANANA.PEEL", "BANANA.WEIGHT", "BANANA"},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			as := assert.New(t)
+			dist := regular.NewRegularDistribution()
+			as.NoError(dist.AddEntry(1, 1))
+			acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+			acc.SetNameSanitizer(testCase.sanitizer)
+
+			acc.AddHistogram("banana", map[string]interface{}{"peel": dist}, map[string]string{}, time.Now())
+			acc.AddGauge("banana", map[string]interface{}{"weight": 1.0}, map[string]string{}, time.Now())
+			acc.AddGauge("banana", map[string]interface{}{"value": 1.0}, map[string]string{}, time.Now())
+
+			var names []string
+			forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+				for i := 0; i < ms.Len(); i++ {
+					names = append(names, ms.At(i).Name())
+				}
+			})
+			as.Equal(testCase.want, names)
+		})
+	}
+}
+
+func TestNameJoiner(t *testing.T) {
+	testCases := map[string]struct {
+		separator string
+		sanitizer func(string) string
+		want      []string
+	}{
+		"Underscore": {
+			separator: "_",
+			want:      []string{"banana_peel", "banana_peel size", "banana"},
+		},
+		"Dot": {
+			separator: ".",
+			want:      []string{"banana.peel", "banana.peel size", "banana"},
+		},
+		"Empty": {
+			separator: "",
+			want:      []string{"bananapeel", "bananapeel size", "banana"},
+		},
+		"DotWithSanitizer": {
+			separator: ".",
+			sanitizer: SanitizeUnderscore,
+			want:      []string{"banana.peel", "banana.peel_size", "banana"},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			as := assert.New(t)
+			dist := regular.NewRegularDistribution()
+			as.NoError(dist.AddEntry(1, 1))
+			acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+			acc.SetNameJoiner(testCase.separator)
+			acc.SetNameSanitizer(testCase.sanitizer)
+
+			acc.AddHistogram("banana", map[string]interface{}{"peel": dist}, map[string]string{}, time.Now())
+			acc.AddGauge("banana", map[string]interface{}{"peel size": 1.0}, map[string]string{}, time.Now())
+			acc.AddGauge("banana", map[string]interface{}{"value": 1.0}, map[string]string{}, time.Now())
+
+			var names []string
+			forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+				for i := 0; i < ms.Len(); i++ {
+					names = append(names, ms.At(i).Name())
+				}
+			})
+			as.Equal(testCase.want, names)
+		})
+	}
+}
+
+func TestAddHistogramBuckets(t *testing.T) {
+	as := assert.New(t)
+	dist := regular.NewRegularDistribution()
+	as.NoError(dist.AddEntry(5, 2))
+	as.NoError(dist.AddEntry(1, 1))
+	as.NoError(dist.AddEntry(10, 4))
+	as.NoError(dist.AddEntry(2.5, 3))
+
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{}, time.Now())
+
+	dp := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+	as.Equal([]float64{1, 2.5, 5, 10}, dp.ExplicitBounds().AsRaw())
+	as.Equal([]uint64{1, 3, 2, 4, 0}, dp.BucketCounts().AsRaw())
+	var total uint64
+	for _, count := range dp.BucketCounts().AsRaw() {
+		total += count
+	}
+	as.Equal(uint64(dist.SampleCount()), total)
+	as.Equal(total, dp.Count())
+}
+
+func TestAddHistogramAsStats(t *testing.T) {
+	as := assert.New(t)
+	dist := regular.NewRegularDistribution()
+	as.NoError(dist.AddEntry(1, 1))
+	as.NoError(dist.AddEntry(5, 2))
+	as.NoError(dist.AddEntry(10, 1))
+
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.HistogramAsStats = true
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{defaultInstanceId: defaultInstanceIdValue}, time.Now())
+
+	metrics := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(4, metrics.Len())
+	want := map[string]struct {
+		metricType pmetric.MetricType
+		value      float64
+	}{
+		"latency_min":   {pmetric.MetricTypeGauge, 1},
+		"latency_max":   {pmetric.MetricTypeGauge, 10},
+		"latency_sum":   {pmetric.MetricTypeSum, 21},
+		"latency_count": {pmetric.MetricTypeSum, 4},
+	}
+	for i := 0; i < metrics.Len(); i++ {
+		m := metrics.At(i)
+		as.NotEqual(pmetric.MetricTypeHistogram, m.Type())
+		expected, ok := want[m.Name()]
+		as.True(ok, m.Name())
+		as.Equal(expected.metricType, m.Type())
+		var dp pmetric.NumberDataPoint
+		if m.Type() == pmetric.MetricTypeSum {
+			as.Equal(pmetric.AggregationTemporalityDelta, m.Sum().AggregationTemporality())
+			dp = m.Sum().DataPoints().At(0)
+		} else {
+			dp = m.Gauge().DataPoints().At(0)
+		}
+		as.Equal(expected.value, dp.DoubleValue())
+		as.Equal(generateExpectedAttributes(), dp.Attributes())
+		delete(want, m.Name())
+	}
+	as.Empty(want)
 }
 
 func Test_Accumulator_WithUnsupportedValueAndEmptyFields(t *testing.T) {
@@ -194,6 +356,33 @@ func Test_Accumulator_WithUnsupportedValueAndEmptyFields(t *testing.T) {
 	as.Equal(0, otelMetrics.ResourceMetrics().Len())
 }
 
+func Test_Accumulator_WithValidFieldAndEmptyTags(t *testing.T) {
+	for _, drop := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Drop=%v", drop), func(t *testing.T) {
+			as := assert.New(t)
+			acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+			acc.cfg.DropUntaggedMetrics = drop
+
+			acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, time.Now())
+			acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 2.0}, map[string]string{"cpu": "cpu0"}, time.Now())
+
+			otelMetrics := acc.GetOtelMetrics()
+			var attributes []map[string]any
+			forEachMetricSlice(otelMetrics, func(ms pmetric.MetricSlice) {
+				for i := 0; i < ms.Len(); i++ {
+					as.Equal(metric.DecorateMetricName("cpu", "usage_idle"), ms.At(i).Name())
+					attributes = append(attributes, ms.At(i).Gauge().DataPoints().At(0).Attributes().AsRaw())
+				}
+			})
+			if drop {
+				as.Equal([]map[string]any{{"cpu": "cpu0"}}, attributes)
+			} else {
+				as.Equal([]map[string]any{{}, {"cpu": "cpu0"}}, attributes)
+			}
+		})
+	}
+}
+
 func Test_ModifyMetricAndConvertMetricValue(t *testing.T) {
 	as := assert.New(t)
 	cfg := &models.InputConfig{
@@ -289,6 +478,88 @@ func Test_ModifyMetricAndConvertMetricValue(t *testing.T) {
 	}
 }
 
+func Test_ModifyMetricAndConvertMetricValue_StringFieldsAsAttributes(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.SetStringFieldsAsAttributes(true)
+
+	testCases := map[string]struct {
+		tags       map[string]string
+		fields     map[string]interface{}
+		wantErrStr string
+		wantFields map[string]interface{}
+		wantTags   map[string]string
+	}{
+		"WithMixedFields": {
+			tags:       map[string]string{"instance_id": "mock"},
+			fields:     map[string]interface{}{"reads": int64(3), "error": false, "name": "sda"},
+			wantFields: map[string]interface{}{"reads": int64(3), "error": int64(0)},
+			wantTags:   map[string]string{"instance_id": "mock", "name": "sda"},
+		},
+		"WithEmptyString": {
+			tags:       map[string]string{},
+			fields:     map[string]interface{}{"reads": int64(3), "serial": ""},
+			wantFields: map[string]interface{}{"reads": int64(3)},
+			wantTags:   map[string]string{},
+		},
+		"WithOnlyStrings": {
+			tags:       map[string]string{},
+			fields:     map[string]interface{}{"name": "sda", "serial": "123"},
+			wantErrStr: "empty metrics after converting fields",
+		},
+		"WithTagCollision": {
+			tags:       map[string]string{"name": "tag"},
+			fields:     map[string]interface{}{"reads": int64(3), "name": "field"},
+			wantFields: map[string]interface{}{"reads": int64(3)},
+			wantTags:   map[string]string{"name": "tag"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(_ *testing.T) {
+			m := testutil.MustMetric("diskio", testCase.tags, testCase.fields, time.Now(), telegraf.Gauge)
+			got, err := acc.modifyMetricAndConvertToOtelValue(m)
+			if testCase.wantErrStr != "" {
+				as.ErrorContains(err, testCase.wantErrStr)
+				as.Nil(got)
+				return
+			}
+			as.NoError(err)
+			as.Equal(testCase.wantFields, got.Fields())
+			as.Equal(testCase.wantTags, got.Tags())
+		})
+	}
+
+	acc.AddGauge("diskio", map[string]interface{}{"reads": int64(3), "writes": int64(4), "name": "sda"}, map[string]string{}, time.Now())
+	metrics := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(2, metrics.Len())
+	for i := 0; i < metrics.Len(); i++ {
+		as.Equal(map[string]any{"name": "sda"}, metrics.At(i).Gauge().DataPoints().At(0).Attributes().AsRaw())
+	}
+
+	// the string fields are dropped when disabled
+	acc.SetStringFieldsAsAttributes(false)
+	got, err := acc.modifyMetricAndConvertToOtelValue(testutil.MustMetric("diskio", map[string]string{}, map[string]interface{}{"reads": int64(3), "name": "sda"}, time.Now(), telegraf.Gauge))
+	as.NoError(err)
+	as.Equal(map[string]interface{}{"reads": int64(3)}, got.Fields())
+	as.Empty(got.Tags())
+}
+
+func Test_Accumulator_UnnamedMeasurement(t *testing.T) {
+	as := assert.New(t)
+	fields := map[string]interface{}{"usage": 1.0}
+
+	acc := newOtelAccumulatorWithConfig(as, nil, false, &models.InputConfig{Name: "exec"})
+	acc.AddGauge("", fields, map[string]string{}, time.Now())
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(1, otelMetrics.ResourceMetrics().Len())
+	as.Equal(metric.DecorateMetricName("exec", "usage"), otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Name())
+
+	acc.cfg.DropUnnamedMeasurements = true
+	acc.AddGauge("", fields, map[string]string{}, time.Now())
+	as.Equal(pmetric.NewMetrics(), acc.GetOtelMetrics())
+}
+
 func Test_Accumulator_AddMetric(t *testing.T) {
 	t.Helper()
 
@@ -323,6 +594,31 @@ func Test_Accumulator_AddMetric(t *testing.T) {
 
 }
 
+func Test_Accumulator_SetPrecisionForMetric(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.SetPrecision(time.Microsecond)
+	acc.SetPrecisionForMetric("latency", time.Millisecond)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 123456789, time.UTC)
+	acc.AddGauge("latency", map[string]interface{}{"p99": 1.0}, map[string]string{}, now)
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, now)
+	acc.AddMetric(testutil.MustMetric("latency", map[string]string{}, map[string]interface{}{"p50": 1.0}, now, telegraf.Gauge))
+
+	timestamps := map[string]time.Time{}
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			timestamps[ms.At(i).Name()] = ms.At(i).Gauge().DataPoints().At(0).Timestamp().AsTime().UTC()
+		}
+	})
+	as.Equal(map[string]time.Time{
+		metric.DecorateMetricName("latency", "p99"):    now.Round(time.Millisecond),
+		metric.DecorateMetricName("latency", "p50"):    now.Round(time.Millisecond),
+		metric.DecorateMetricName("cpu", "usage_idle"): now.Round(time.Microsecond),
+	}, timestamps)
+	as.Equal(123*time.Millisecond, time.Duration(timestamps[metric.DecorateMetricName("latency", "p99")].Nanosecond()))
+}
+
 func Test_Accumulator_AddMetric_ServiceInput(t *testing.T) {
 	t.Helper()
 
@@ -375,8 +671,133 @@ func Test_Accumulator_AddSum(t *testing.T) {
 	acc.AddSummary("acc_summary_test", telegrafMetricFields, telegrafMetricTags, now)
 
 	otelMetrics := acc.GetOtelMetrics()
-	as.Equal(0, otelMetrics.ResourceMetrics().Len())
-	as.Equal(pmetric.NewMetrics(), otelMetrics)
+	as.Equal(1, otelMetrics.ResourceMetrics().Len())
+	metrics := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(1, metrics.Len())
+	as.Equal(pmetric.MetricTypeSummary, metrics.At(0).Type())
+	datapoint := metrics.At(0).Summary().DataPoints().At(0)
+	as.Equal(uint64(1), datapoint.Count())
+	as.Equal(20.0, datapoint.Sum())
+	as.Equal(generateExpectedAttributes(), datapoint.Attributes())
+
+	// an empty field set produces no metric
+	acc.AddSummary("acc_summary_test", map[string]interface{}{}, telegrafMetricTags, now)
+	as.Equal(pmetric.NewMetrics(), acc.GetOtelMetrics())
+}
+
+func Test_Accumulator_AddSummary(t *testing.T) {
+	t.Helper()
+
+	as := assert.New(t)
+
+	testCases := []struct {
+		name                 string
+		telegrafMetricName   string
+		telegrafMetricTags   map[string]string
+		expectedDPAttributes pcommon.Map
+		isServiceInput       bool
+	}{
+		{
+			name:                 "OtelAccumulator with AddSummary",
+			telegrafMetricName:   "acc_summary_test",
+			telegrafMetricTags:   map[string]string{defaultInstanceId: defaultInstanceIdValue},
+			expectedDPAttributes: generateExpectedAttributes(),
+			isServiceInput:       false,
+		},
+		{
+			name:                 "OtelAccumulator with AddSummary For ServiceInput",
+			telegrafMetricName:   "acc_summary_test",
+			telegrafMetricTags:   map[string]string{defaultInstanceId: defaultInstanceIdValue},
+			expectedDPAttributes: generateExpectedAttributes(),
+			isServiceInput:       true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(_ *testing.T) {
+			sink := new(consumertest.MetricsSink)
+			acc := newOtelAccumulatorWithTestRunningInputs(as, sink, tc.isServiceInput)
+
+			now := time.Now()
+			telegrafMetricFields := map[string]interface{}{
+				"timing_count": int64(4),
+				"timing_sum":   10.0,
+				"timing_p50":   2.0,
+				"timing_p999":  4.5,
+				"timing_p99":   4.0,
+				"error":        "timeout",
+				"requests":     int64(3),
+			}
+			acc.AddSummary(tc.telegrafMetricName, telegrafMetricFields, tc.telegrafMetricTags, now)
+
+			var otelMetrics pmetric.Metrics
+			if tc.isServiceInput {
+				as.Len(sink.AllMetrics(), 1)
+				otelMetrics = sink.AllMetrics()[0]
+			} else {
+				as.Len(sink.AllMetrics(), 0)
+				otelMetrics = acc.GetOtelMetrics()
+			}
+
+			metrics := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			as.Equal(2, metrics.Len())
+			for i := 0; i < metrics.Len(); i++ {
+				m := metrics.At(i)
+				as.Equal(pmetric.MetricTypeSummary, m.Type())
+				datapoint := m.Summary().DataPoints().At(0)
+				as.Equal(tc.expectedDPAttributes, datapoint.Attributes())
+				as.Equal(pcommon.NewTimestampFromTime(now), datapoint.Timestamp())
+				switch m.Name() {
+				case metric.DecorateMetricName(tc.telegrafMetricName, "timing"):
+					as.Equal(uint64(4), datapoint.Count())
+					as.Equal(10.0, datapoint.Sum())
+					var quantiles, values []float64
+					for j := 0; j < datapoint.QuantileValues().Len(); j++ {
+						quantiles = append(quantiles, datapoint.QuantileValues().At(j).Quantile())
+						values = append(values, datapoint.QuantileValues().At(j).Value())
+					}
+					as.Equal([]float64{0.5, 0.99, 0.999}, quantiles)
+					as.Equal([]float64{2, 4, 4.5}, values)
+				case metric.DecorateMetricName(tc.telegrafMetricName, "requests"):
+					as.Equal(uint64(1), datapoint.Count())
+					as.Equal(3.0, datapoint.Sum())
+					as.Equal(0, datapoint.QuantileValues().Len())
+				default:
+					as.Fail("unexpected metric", m.Name())
+				}
+			}
+		})
+	}
+}
+
+func Test_Accumulator_ConcurrentAdd(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+
+	const producers, metricsPerProducer = 16, 200
+	var wg sync.WaitGroup
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func(producer int) {
+			defer wg.Done()
+			for j := 0; j < metricsPerProducer; j++ {
+				acc.AddGauge("cpu", map[string]interface{}{"usage_idle": float64(j)}, map[string]string{"producer": strconv.Itoa(producer)}, time.Now())
+			}
+		}(i)
+	}
+
+	// a single consumer drains while the producers add
+	done := make(chan struct{})
+	var total int
+	go func() {
+		defer close(done)
+		for k := 0; k < 50; k++ {
+			total += acc.GetOtelMetrics().DataPointCount()
+		}
+	}()
+	wg.Wait()
+	<-done
+	total += acc.GetOtelMetrics().DataPointCount()
+	as.Equal(producers*metricsPerProducer, total)
 }
 
 func Test_Accumulator_AddError(t *testing.T) {
@@ -394,3 +815,158 @@ func Test_Accumulator_AddError(t *testing.T) {
 	// {"level":"error","msg":"Error with adapter","error":"bar"}
 	// {"level":"error","msg":"Error with adapter","error":"baz"}
 }
+
+func Test_Accumulator_SetErrorRateLimit(t *testing.T) {
+	as := assert.New(t)
+	core, logs := observer.New(zap.ErrorLevel)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.logger = zap.New(core)
+	now := time.Now()
+	acc.cfg.Clock = func() time.Time { return now }
+	acc.SetErrorRateLimit(time.Minute, 2)
+
+	for i := 0; i < 10; i++ {
+		acc.AddError(fmt.Errorf("foo"))
+	}
+	as.Equal(2, logs.Len())
+	as.EqualValues(8, acc.SuppressedErrors())
+
+	// a different error reports the repeated one and is logged
+	acc.AddError(fmt.Errorf("bar"))
+	acc.AddError(fmt.Errorf("baz"))
+	as.Equal(5, logs.Len())
+	repeated := logs.All()[2]
+	as.Equal("Error with adapter repeated", repeated.Message)
+	as.Equal(map[string]any{"error": "foo", "times": int64(8)}, repeated.ContextMap())
+
+	// the window ends
+	acc.AddError(fmt.Errorf("baz"))
+	acc.AddError(fmt.Errorf("baz"))
+	as.Equal(6, logs.Len())
+	now = now.Add(time.Minute)
+	acc.AddError(fmt.Errorf("baz"))
+	as.Equal(8, logs.Len())
+
+	// the suppressed errors are reported on flush
+	acc.AddError(fmt.Errorf("baz"))
+	acc.AddError(fmt.Errorf("baz"))
+	as.Equal(9, logs.Len())
+	acc.GetOtelMetrics()
+	as.Equal(10, logs.Len())
+	as.EqualValues(10, acc.SuppressedErrors())
+}
+
+func Test_Accumulator_OnAfterDrain(t *testing.T) {
+	as := assert.New(t)
+
+	var counts []int
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.OnAfterDrain = func(count int) {
+		counts = append(counts, count)
+	}
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0, "usage_user": 2.0}, map[string]string{}, time.Now())
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(1)}, map[string]string{}, time.Now())
+	as.Equal(3, acc.GetOtelMetrics().DataPointCount())
+	acc.GetOtelMetrics()
+
+	as.Equal([]int{3, 0}, counts)
+}
+
+func Test_Accumulator_Heartbeat(t *testing.T) {
+	as := assert.New(t)
+
+	now := time.Now()
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.HeartbeatMetricName = "heartbeat"
+	acc.cfg.Clock = func() time.Time { return now }
+
+	assertHeartbeat := func(otelMetrics pmetric.Metrics) {
+		rms := otelMetrics.ResourceMetrics()
+		as.Greater(rms.Len(), 0)
+		m := rms.At(rms.Len() - 1).ScopeMetrics().At(0).Metrics().At(0)
+		as.Equal("heartbeat", m.Name())
+		dp := m.Gauge().DataPoints().At(0)
+		as.Equal(int64(1), dp.IntValue())
+		as.Equal(pcommon.NewTimestampFromTime(now), dp.Timestamp())
+	}
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, now)
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+	assertHeartbeat(otelMetrics)
+
+	otelMetrics = acc.GetOtelMetrics()
+	as.Equal(1, otelMetrics.ResourceMetrics().Len())
+	assertHeartbeat(otelMetrics)
+}
+
+func Test_Accumulator_SetCounterTemporality(t *testing.T) {
+	testCases := map[string]struct {
+		temporality     *pmetric.AggregationTemporality
+		wantTemporality pmetric.AggregationTemporality
+	}{
+		"Default": {
+			wantTemporality: pmetric.AggregationTemporalityCumulative,
+		},
+		"Delta": {
+			temporality:     func() *pmetric.AggregationTemporality { v := pmetric.AggregationTemporalityDelta; return &v }(),
+			wantTemporality: pmetric.AggregationTemporalityDelta,
+		},
+		"Cumulative": {
+			temporality:     func() *pmetric.AggregationTemporality { v := pmetric.AggregationTemporalityCumulative; return &v }(),
+			wantTemporality: pmetric.AggregationTemporalityCumulative,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			as := assert.New(t)
+			acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+			if testCase.temporality != nil {
+				acc.SetCounterTemporality(*testCase.temporality)
+			}
+
+			acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(1)}, map[string]string{}, time.Now())
+
+			sum := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum()
+			as.Equal(testCase.wantTemporality, sum.AggregationTemporality())
+			as.True(sum.IsMonotonic())
+		})
+	}
+}
+
+func Test_Accumulator_SetCounterMonotonic(t *testing.T) {
+	testCases := map[string]struct {
+		monotonic     *bool
+		wantMonotonic map[string]bool
+	}{
+		"Default": {
+			wantMonotonic: map[string]bool{"net": true, "resettable": false},
+		},
+		"NonMonotonic": {
+			monotonic:     func() *bool { v := false; return &v }(),
+			wantMonotonic: map[string]bool{"net": false, "resettable": false},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			as := assert.New(t)
+			acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+			acc.cfg.CounterMonotonicByMeasurement = map[string]bool{"resettable": false}
+			if testCase.monotonic != nil {
+				acc.SetCounterMonotonic(*testCase.monotonic)
+			}
+
+			acc.AddCounter("net", map[string]interface{}{"value": int64(1)}, map[string]string{}, time.Now())
+			acc.AddCounter("resettable", map[string]interface{}{"value": int64(1)}, map[string]string{}, time.Now())
+
+			monotonic := map[string]bool{}
+			forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+				for i := 0; i < ms.Len(); i++ {
+					monotonic[ms.At(i).Name()] = ms.At(i).Sum().IsMonotonic()
+				}
+			})
+			as.Equal(testCase.wantMonotonic, monotonic)
+		})
+	}
+}
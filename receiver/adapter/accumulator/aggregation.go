@@ -0,0 +1,112 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"go.uber.org/zap"
+)
+
+// GaugeAggregation decides which value of a gauge field is emitted when it is added several times per interval.
+type GaugeAggregation string
+
+const (
+	// GaugeAggregationLast emits every value as is.
+	GaugeAggregationLast GaugeAggregation = "Last"
+	// GaugeAggregationMean emits the mean of the values added during the interval.
+	GaugeAggregationMean GaugeAggregation = "Mean"
+	// GaugeAggregationMax emits the maximum of the values added during the interval.
+	GaugeAggregationMax GaugeAggregation = "Max"
+	// GaugeAggregationMin emits the minimum of the values added during the interval.
+	GaugeAggregationMin GaugeAggregation = "Min"
+)
+
+// aggregatedGauge holds the values of a gauge series aggregated until the next flush.
+type aggregatedGauge struct {
+	measurement string
+	field       string
+	tags        map[string]string
+	aggregation GaugeAggregation
+	sum         float64
+	count       int
+	min         float64
+	max         float64
+	timestamp   time.Time
+}
+
+// value returns the aggregated value of the gauge.
+func (g *aggregatedGauge) value() float64 {
+	switch g.aggregation {
+	case GaugeAggregationMax:
+		return g.max
+	case GaugeAggregationMin:
+		return g.min
+	default:
+		return g.sum / float64(g.count)
+	}
+}
+
+// aggregateGauges removes the gauge fields configured in GaugeAggregationByField with an aggregation other than
+// Last from the metric and aggregates them into the gauges emitted on the next GetOtelMetrics.
+func (o *otelAccumulator) aggregateGauges(m telegraf.Metric) {
+	if len(o.cfg.GaugeAggregationByField) == 0 || m.Type() != telegraf.Gauge {
+		return
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for field, v := range m.Fields() {
+		aggregation := o.cfg.GaugeAggregationByField[field]
+		if aggregation == "" || aggregation == GaugeAggregationLast {
+			continue
+		}
+		value, ok := toFloat64(v)
+		if !ok {
+			continue
+		}
+
+		key := seriesKey(metricName(&o.cfg, m.Name(), field), m.Tags())
+		g, ok := o.aggregatedGauges[key]
+		if !ok {
+			g = &aggregatedGauge{
+				measurement: m.Name(),
+				field:       field,
+				tags:        m.Tags(),
+				aggregation: aggregation,
+				min:         value,
+				max:         value,
+			}
+			if o.aggregatedGauges == nil {
+				o.aggregatedGauges = map[string]*aggregatedGauge{}
+			}
+			o.aggregatedGauges[key] = g
+		}
+		g.sum += value
+		g.count++
+		g.min = min(g.min, value)
+		g.max = max(g.max, value)
+		if m.Time().After(g.timestamp) {
+			g.timestamp = m.Time()
+		}
+		m.RemoveField(field)
+	}
+}
+
+// flushAggregatedGauges converts the aggregated gauges and appends them to the accumulated metrics.
+// The caller must hold the mutex.
+func (o *otelAccumulator) flushAggregatedGauges() {
+	for _, g := range o.aggregatedGauges {
+		fields := map[string]interface{}{g.field: g.value()}
+		oMetric, err := convertTelegrafToOtelMetrics(&o.cfg, g.measurement, fields, g.tags, telegraf.Gauge, g.timestamp)
+		if err != nil {
+			o.logger.Warn("Convert aggregated gauge failed", zap.String("field", g.field), zap.Error(err))
+			continue
+		}
+		o.processOtelMetrics(oMetric)
+		o.appendResourceMetrics(oMetric)
+	}
+	o.aggregatedGauges = nil
+}
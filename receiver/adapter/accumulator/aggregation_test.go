@@ -0,0 +1,52 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/metric"
+)
+
+func TestGaugeAggregation(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.GaugeAggregationByField = map[string]GaugeAggregation{
+		"usage_idle":   GaugeAggregationMean,
+		"usage_user":   GaugeAggregationMax,
+		"usage_system": GaugeAggregationMin,
+		"usage_steal":  GaugeAggregationLast,
+	}
+
+	now := time.Now()
+	tags := map[string]string{"cpu": "cpu0"}
+	for i, value := range []float64{2, 4, 6} {
+		fields := map[string]interface{}{"usage_idle": value, "usage_user": value, "usage_system": value, "usage_steal": value}
+		acc.AddGauge("cpu", fields, tags, now.Add(time.Duration(i)*time.Second))
+	}
+
+	otelMetrics := acc.GetOtelMetrics()
+	values := map[string][]float64{}
+	timestamps := map[string]pcommon.Timestamp{}
+	for i := 0; i < otelMetrics.ResourceMetrics().Len(); i++ {
+		metrics := otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics()
+		for j := 0; j < metrics.Len(); j++ {
+			dp := metrics.At(j).Gauge().DataPoints().At(0)
+			values[metrics.At(j).Name()] = append(values[metrics.At(j).Name()], dp.DoubleValue())
+			timestamps[metrics.At(j).Name()] = dp.Timestamp()
+			as.Equal(map[string]any{"cpu": "cpu0"}, dp.Attributes().AsRaw())
+		}
+	}
+	as.Equal([]float64{4}, values[metric.DecorateMetricName("cpu", "usage_idle")])
+	as.Equal([]float64{6}, values[metric.DecorateMetricName("cpu", "usage_user")])
+	as.Equal([]float64{2}, values[metric.DecorateMetricName("cpu", "usage_system")])
+	as.Equal([]float64{2, 4, 6}, values[metric.DecorateMetricName("cpu", "usage_steal")])
+	as.Equal(pcommon.NewTimestampFromTime(now.Add(2*time.Second)), timestamps[metric.DecorateMetricName("cpu", "usage_idle")])
+
+	as.Equal(0, acc.GetOtelMetrics().ResourceMetrics().Len())
+}
@@ -0,0 +1,129 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// DrainBatched returns the accumulated metrics, like GetOtelMetrics, split into batches of at most maxPerBatch
+// data points each (e.g. for exporters with a per request limit). The metrics are returned as a single batch when
+// maxPerBatch is not positive, and no batch is returned when there is no data point.
+func (o *otelAccumulator) DrainBatched(maxPerBatch int) []pmetric.Metrics {
+	return splitMetricsByDataPoints(o.GetOtelMetrics(), maxPerBatch)
+}
+
+// splitMetricsByDataPoints splits the metrics into batches of at most maxPerBatch data points, keeping the resource,
+// scope and metric of each data point.
+func splitMetricsByDataPoints(md pmetric.Metrics, maxPerBatch int) []pmetric.Metrics {
+	count := md.DataPointCount()
+	if count == 0 {
+		return nil
+	}
+	if maxPerBatch <= 0 || count <= maxPerBatch {
+		return []pmetric.Metrics{md}
+	}
+
+	var batches []pmetric.Metrics
+	var batch pmetric.Metrics
+	var size int
+	var dstRM pmetric.ResourceMetrics
+	var dstSM pmetric.ScopeMetrics
+	var dstM pmetric.Metric
+	// the resource, scope and metric are appended to a batch with their first data point in it
+	var newRM, newSM, newM bool
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		newRM = true
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			newSM = true
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				m := sm.Metrics().At(k)
+				newM = true
+				for l := 0; l < dataPointLen(m); l++ {
+					if size == 0 || size == maxPerBatch {
+						batch = pmetric.NewMetrics()
+						batches = append(batches, batch)
+						size = 0
+						newRM, newSM, newM = true, true, true
+					}
+					if newRM {
+						dstRM = batch.ResourceMetrics().AppendEmpty()
+						rm.Resource().CopyTo(dstRM.Resource())
+						dstRM.SetSchemaUrl(rm.SchemaUrl())
+						newRM = false
+					}
+					if newSM {
+						dstSM = dstRM.ScopeMetrics().AppendEmpty()
+						sm.Scope().CopyTo(dstSM.Scope())
+						dstSM.SetSchemaUrl(sm.SchemaUrl())
+						newSM = false
+					}
+					if newM {
+						dstM = dstSM.Metrics().AppendEmpty()
+						copyMetricDescriptor(m, dstM)
+						newM = false
+					}
+					copyDataPoint(m, l, dstM)
+					size++
+				}
+			}
+		}
+	}
+	return batches
+}
+
+// dataPointLen returns the number of data points of the metric.
+func dataPointLen(m pmetric.Metric) int {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		return m.Gauge().DataPoints().Len()
+	case pmetric.MetricTypeSum:
+		return m.Sum().DataPoints().Len()
+	case pmetric.MetricTypeHistogram:
+		return m.Histogram().DataPoints().Len()
+	case pmetric.MetricTypeExponentialHistogram:
+		return m.ExponentialHistogram().DataPoints().Len()
+	case pmetric.MetricTypeSummary:
+		return m.Summary().DataPoints().Len()
+	}
+	return 0
+}
+
+// copyMetricDescriptor copies the name, description, unit and type of the metric without its data points.
+func copyMetricDescriptor(src pmetric.Metric, dst pmetric.Metric) {
+	dst.SetName(src.Name())
+	dst.SetDescription(src.Description())
+	dst.SetUnit(src.Unit())
+	switch src.Type() {
+	case pmetric.MetricTypeGauge:
+		dst.SetEmptyGauge()
+	case pmetric.MetricTypeSum:
+		dst.SetEmptySum().SetAggregationTemporality(src.Sum().AggregationTemporality())
+		dst.Sum().SetIsMonotonic(src.Sum().IsMonotonic())
+	case pmetric.MetricTypeHistogram:
+		dst.SetEmptyHistogram().SetAggregationTemporality(src.Histogram().AggregationTemporality())
+	case pmetric.MetricTypeExponentialHistogram:
+		dst.SetEmptyExponentialHistogram().SetAggregationTemporality(src.ExponentialHistogram().AggregationTemporality())
+	case pmetric.MetricTypeSummary:
+		dst.SetEmptySummary()
+	}
+}
+
+// copyDataPoint appends the data point at index i of src to the data points of dst, which has the same type.
+func copyDataPoint(src pmetric.Metric, i int, dst pmetric.Metric) {
+	switch src.Type() {
+	case pmetric.MetricTypeGauge:
+		src.Gauge().DataPoints().At(i).CopyTo(dst.Gauge().DataPoints().AppendEmpty())
+	case pmetric.MetricTypeSum:
+		src.Sum().DataPoints().At(i).CopyTo(dst.Sum().DataPoints().AppendEmpty())
+	case pmetric.MetricTypeHistogram:
+		src.Histogram().DataPoints().At(i).CopyTo(dst.Histogram().DataPoints().AppendEmpty())
+	case pmetric.MetricTypeExponentialHistogram:
+		src.ExponentialHistogram().DataPoints().At(i).CopyTo(dst.ExponentialHistogram().DataPoints().AppendEmpty())
+	case pmetric.MetricTypeSummary:
+		src.Summary().DataPoints().At(i).CopyTo(dst.Summary().DataPoints().AppendEmpty())
+	}
+}
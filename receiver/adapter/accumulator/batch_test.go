@@ -0,0 +1,48 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestDrainBatched(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+
+	now := time.Now()
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0, "usage_user": 2.0, "usage_system": 3.0}, map[string]string{"cpu": "cpu0"}, now)
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0, "usage_user": 2.0, "usage_system": 3.0}, map[string]string{"cpu": "cpu1"}, now)
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(1), "bytes_sent": int64(2), "packets_recv": int64(3), "packets_sent": int64(4)}, map[string]string{}, now)
+
+	batches := acc.DrainBatched(4)
+	as.Len(batches, 3)
+	var sizes []int
+	sums := 0
+	for _, batch := range batches {
+		sizes = append(sizes, batch.DataPointCount())
+		for i := 0; i < batch.ResourceMetrics().Len(); i++ {
+			metrics := batch.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics()
+			for j := 0; j < metrics.Len(); j++ {
+				if metrics.At(j).Type() == pmetric.MetricTypeSum {
+					as.True(metrics.At(j).Sum().IsMonotonic())
+					as.Equal(pmetric.AggregationTemporalityCumulative, metrics.At(j).Sum().AggregationTemporality())
+					sums++
+				}
+			}
+		}
+	}
+	as.Equal([]int{4, 4, 2}, sizes)
+	as.Equal(4, sums)
+
+	as.Empty(acc.DrainBatched(4))
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, now)
+	batches = acc.DrainBatched(0)
+	as.Len(batches, 1)
+	as.Equal(1, batches[0].DataPointCount())
+}
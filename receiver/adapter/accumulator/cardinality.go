@@ -0,0 +1,119 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// hyperLogLogPrecision is the number of bits of the hash selecting a register. The 4096 registers use 4KB per
+// metric name for a standard error of about 1.6%.
+const hyperLogLogPrecision = 12
+
+// hyperLogLog estimates the number of distinct hashes added to it.
+type hyperLogLog struct {
+	registers [1 << hyperLogLogPrecision]uint8
+}
+
+// add records the hash in the register selected by its first bits, keeping the longest run of leading zeros seen
+// in the remaining bits.
+func (h *hyperLogLog) add(hash uint64) {
+	index := hash >> (64 - hyperLogLogPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<hyperLogLogPrecision|1<<(hyperLogLogPrecision-1))) + 1
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// estimate returns the estimated number of distinct hashes, using linear counting for the small cardinalities.
+func (h *hyperLogLog) estimate() uint64 {
+	m := float64(len(h.registers))
+	var sum float64
+	var zeros int
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(estimate))
+}
+
+// cardinalityEstimator holds a HyperLogLog sketch of the series of each metric name.
+type cardinalityEstimator struct {
+	mutex    sync.Mutex
+	sketches map[string]*hyperLogLog
+}
+
+// estimateCardinality adds the series of the data points of the metrics to the sketch of their metric name, when
+// EstimateCardinality is enabled. It is called with the metrics as emitted, so the series built on flush (e.g. the
+// heartbeat and the merged histograms) are counted too.
+func (o *otelAccumulator) estimateCardinality(metrics pmetric.Metrics) {
+	if !o.cfg.EstimateCardinality {
+		return
+	}
+
+	c := &o.cardinality
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		rm := metrics.ResourceMetrics().At(i)
+		resourceKey := seriesKey("", attributesAsStrings(rm.Resource().Attributes()))
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			ms := rm.ScopeMetrics().At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				name := ms.At(k).Name()
+				sketch, ok := c.sketches[name]
+				if !ok {
+					if c.sketches == nil {
+						c.sketches = map[string]*hyperLogLog{}
+					}
+					sketch = &hyperLogLog{}
+					c.sketches[name] = sketch
+				}
+				forEachDataPointAttributes(ms.At(k), func(attributes pcommon.Map) {
+					sketch.add(hashSeries(seriesKey("", attributesAsStrings(attributes)) + "\x00" + resourceKey))
+				})
+			}
+		}
+	}
+}
+
+// hashSeries hashes the series key with FNV-1a, followed by the finalizer of MurmurHash3 so the bits used by the
+// sketch are evenly distributed.
+func hashSeries(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// EstimatedCardinality returns the estimated number of distinct series (i.e. data point and resource attributes) of
+// the metric name since the accumulator was created, so operators can be warned before the number of CloudWatch
+// metrics explodes. It is 0 unless EstimateCardinality is enabled.
+func (o *otelAccumulator) EstimatedCardinality(metricName string) uint64 {
+	c := &o.cardinality
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	sketch, ok := c.sketches[metricName]
+	if !ok {
+		return 0
+	}
+	return sketch.estimate()
+}
@@ -0,0 +1,57 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/metric"
+)
+
+func TestEstimatedCardinality(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EstimateCardinality = true
+	name := metric.DecorateMetricName("requests", "count")
+
+	as.Equal(uint64(0), acc.EstimatedCardinality(name))
+	for _, distinct := range []int{10, 1000, 20000} {
+		for i := 0; i < distinct; i++ {
+			tags := map[string]string{"path": fmt.Sprintf("/path/%d", i%100), "user": fmt.Sprintf("user%d", i/100)}
+			acc.AddGauge("requests", map[string]interface{}{"count": 1.0}, tags, time.Now())
+			// the repeated series are not counted again
+			acc.AddGauge("requests", map[string]interface{}{"count": 2.0}, tags, time.Now())
+		}
+		acc.GetOtelMetrics()
+		as.InEpsilon(distinct, acc.EstimatedCardinality(name), 0.05, "distinct=%d", distinct)
+	}
+	as.Equal(uint64(0), acc.EstimatedCardinality(metric.DecorateMetricName("requests", "other")))
+}
+
+func TestEstimatedCardinalityOnFlush(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EstimateCardinality = true
+	acc.cfg.HeartbeatMetricName = "heartbeat"
+	acc.cfg.ReassembleSummaryByQuantileTag = "quantile"
+	name := metric.DecorateMetricName("http", "latency")
+
+	for i := 0; i < 10; i++ {
+		for _, quantile := range []string{"0.5", "0.99"} {
+			tags := map[string]string{"path": fmt.Sprintf("/path/%d", i), "quantile": quantile}
+			acc.AddGauge("http", map[string]interface{}{"latency": 1.0}, tags, time.Now())
+		}
+	}
+	// the heartbeat and the reassembled summaries are only built on flush
+	as.Equal(uint64(0), acc.EstimatedCardinality("heartbeat"))
+	as.Equal(uint64(0), acc.EstimatedCardinality(name))
+	acc.GetOtelMetrics()
+	acc.GetOtelMetrics()
+	as.Equal(uint64(1), acc.EstimatedCardinality("heartbeat"))
+	as.Equal(uint64(10), acc.EstimatedCardinality(name))
+}
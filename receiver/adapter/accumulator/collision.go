@@ -0,0 +1,151 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// CollisionResolution decides which value is kept when several source metrics end up in the same series (i.e. the
+// same metric name and attributes) within one flush, e.g. after renames.
+type CollisionResolution string
+
+const (
+	// CollisionFirst keeps the first value of the series.
+	CollisionFirst CollisionResolution = "First"
+	// CollisionLast keeps the last value of the series.
+	CollisionLast CollisionResolution = "Last"
+	// CollisionSum keeps the first data point of the series with the sum of the values.
+	CollisionSum CollisionResolution = "Sum"
+	// CollisionError reports the collision with AddError and drops all the values of the series.
+	CollisionError CollisionResolution = "Error"
+)
+
+// collidingSeries tracks the data points of a series seen within one flush.
+type collidingSeries struct {
+	name     string
+	count    int
+	seen     int
+	intSum   int64
+	floatSum float64
+	isFloat  bool
+}
+
+// resolveCollisions applies the CollisionResolution to the gauge and sum data points of the flushed metrics which
+// share a series. The metrics are kept as is when no resolution is configured.
+func (o *otelAccumulator) resolveCollisions(metrics pmetric.Metrics) {
+	if o.cfg.CollisionResolution == "" {
+		return
+	}
+
+	series := map[string]*collidingSeries{}
+	forEachNumberDataPoint(metrics, func(key string, name string, dp pmetric.NumberDataPoint) {
+		s, ok := series[key]
+		if !ok {
+			s = &collidingSeries{name: name}
+			series[key] = s
+		}
+		s.count++
+		if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+			s.intSum += dp.IntValue()
+		} else {
+			s.floatSum += dp.DoubleValue()
+			s.isFloat = true
+		}
+	})
+
+	drop := map[pmetric.NumberDataPoint]struct{}{}
+	forEachNumberDataPoint(metrics, func(key string, _ string, dp pmetric.NumberDataPoint) {
+		s := series[key]
+		s.seen++
+		if s.count == 1 {
+			return
+		}
+		keep := false
+		switch o.cfg.CollisionResolution {
+		case CollisionFirst:
+			keep = s.seen == 1
+		case CollisionLast:
+			keep = s.seen == s.count
+		case CollisionSum:
+			if keep = s.seen == 1; keep && s.isFloat {
+				dp.SetDoubleValue(s.floatSum + float64(s.intSum))
+			} else if keep {
+				dp.SetIntValue(s.intSum)
+			}
+		case CollisionError:
+			if s.seen == 1 {
+				o.AddError(fmt.Errorf("metric (%q) collides with %d other metrics of the same attributes", s.name, s.count-1))
+			}
+		default:
+			keep = true
+		}
+		if !keep {
+			drop[dp] = struct{}{}
+		}
+	})
+	if len(drop) == 0 {
+		return
+	}
+
+	forEachMetricSlice(metrics, func(ms pmetric.MetricSlice) {
+		ms.RemoveIf(func(m pmetric.Metric) bool {
+			dps, ok := numberDataPoints(m)
+			if !ok {
+				return false
+			}
+			dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+				_, ok := drop[dp]
+				return ok
+			})
+			return dps.Len() == 0
+		})
+	})
+	removeEmptyResourceMetrics(metrics)
+}
+
+// forEachNumberDataPoint calls fn with the series key, the metric name and every gauge and sum data point in order.
+func forEachNumberDataPoint(metrics pmetric.Metrics, fn func(key string, name string, dp pmetric.NumberDataPoint)) {
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		rm := metrics.ResourceMetrics().At(i)
+		resourceKey := seriesKey("", attributesAsStrings(rm.Resource().Attributes()))
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			ms := rm.ScopeMetrics().At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				dps, ok := numberDataPoints(ms.At(k))
+				if !ok {
+					continue
+				}
+				for l := 0; l < dps.Len(); l++ {
+					key := seriesKey(ms.At(k).Name(), attributesAsStrings(dps.At(l).Attributes())) + "\x00" + resourceKey
+					fn(key, ms.At(k).Name(), dps.At(l))
+				}
+			}
+		}
+	}
+}
+
+// numberDataPoints returns the data points of the gauge or sum metric.
+func numberDataPoints(m pmetric.Metric) (pmetric.NumberDataPointSlice, bool) {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		return m.Gauge().DataPoints(), true
+	case pmetric.MetricTypeSum:
+		return m.Sum().DataPoints(), true
+	}
+	return pmetric.NumberDataPointSlice{}, false
+}
+
+// attributesAsStrings returns the attributes with their values as strings.
+func attributesAsStrings(attributes pcommon.Map) map[string]string {
+	values := make(map[string]string, attributes.Len())
+	attributes.Range(func(k string, v pcommon.Value) bool {
+		values[k] = v.AsString()
+		return true
+	})
+	return values
+}
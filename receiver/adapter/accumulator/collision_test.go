@@ -0,0 +1,60 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/metric"
+)
+
+func TestCollisionResolution(t *testing.T) {
+	testCases := map[string]struct {
+		resolution CollisionResolution
+		want       []int64
+		wantErrors int
+	}{
+		"None":  {want: []int64{10, 5}},
+		"First": {resolution: CollisionFirst, want: []int64{10}},
+		"Last":  {resolution: CollisionLast, want: []int64{5}},
+		"Sum":   {resolution: CollisionSum, want: []int64{15}},
+		"Error": {resolution: CollisionError, wantErrors: 1},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			as := assert.New(t)
+			core, logs := observer.New(zap.ErrorLevel)
+			acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+			acc.logger = zap.New(core)
+			acc.cfg.CollisionResolution = testCase.resolution
+			acc.cfg.FieldRenameRegex = []FieldRename{{Pattern: "_rx$", Replacement: ""}}
+
+			now := time.Now()
+			tags := map[string]string{"interface": "eth0"}
+			acc.AddGauge("net", map[string]interface{}{"bytes_rx": int64(10)}, tags, now)
+			acc.AddGauge("net", map[string]interface{}{"bytes": int64(5)}, tags, now)
+			// a distinct series does not collide
+			acc.AddGauge("net", map[string]interface{}{"bytes": int64(1)}, map[string]string{"interface": "eth1"}, now)
+
+			var values []int64
+			forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+				for i := 0; i < ms.Len(); i++ {
+					as.Equal(metric.DecorateMetricName("net", "bytes"), ms.At(i).Name())
+					dp := ms.At(i).Gauge().DataPoints().At(0)
+					if iface, _ := dp.Attributes().Get("interface"); iface.Str() == "eth0" {
+						values = append(values, dp.IntValue())
+					}
+				}
+			})
+			as.Equal(testCase.want, values)
+			as.Equal(testCase.wantErrors, logs.Len())
+		})
+	}
+}
@@ -0,0 +1,415 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// NegativeCounterPolicy decides how a negative value of a counter field is emitted, since a monotonic
+// sum cannot decrease below zero.
+type NegativeCounterPolicy string
+
+const (
+	// NegativeCounterDrop drops the negative counter fields.
+	NegativeCounterDrop NegativeCounterPolicy = "Drop"
+	// NegativeCounterAsGauge emits the negative counter fields as gauges.
+	NegativeCounterAsGauge NegativeCounterPolicy = "AsGauge"
+	// NegativeCounterClamp emits the negative counter fields as zero.
+	NegativeCounterClamp NegativeCounterPolicy = "Clamp"
+)
+
+// Config controls how the OtelAccumulator converts Telegraf metrics into OTEL metrics.
+// The zero value keeps the default conversion behavior.
+type Config struct {
+	// HistogramAsStats emits the min, max, sum and count of each distribution as separate
+	// metrics (e.g. name_min, name_max, name_sum, name_count) instead of an OTEL histogram.
+	HistogramAsStats bool
+
+	// ExponentialHistograms converts the distributions into OTEL exponential histograms, with the largest scale
+	// fitting their values, instead of explicit bucket histograms. HistogramAsStats takes precedence.
+	ExponentialHistograms bool
+	// ExponentialFromExplicitBuckets builds the exponential histograms from the explicit buckets of the
+	// distributions (i.e. after HistogramBounds and MaxExplicitBounds) instead of their values, putting the count of
+	// each explicit bucket into the exponential bucket of its midpoint.
+	ExponentialFromExplicitBuckets bool
+
+	// AttributeExtract maps a source tag to a regular expression. Each named capture group
+	// that matches the tag value is added as an attribute named after the group.
+	AttributeExtract map[string]string
+
+	// EnumMappings maps a field name to a table of string values and the numeric values they are
+	// converted to (e.g. status: {up: 1, down: 0}). Unmapped string values are dropped as usual.
+	EnumMappings map[string]map[string]float64
+
+	// InternAttributes deduplicates the attribute keys and values of the data points so large batches
+	// with repeated values share the same strings.
+	InternAttributes bool
+
+	// MetricUnits maps a metric name (e.g. disk_used_percent) to its unit. It takes precedence over
+	// the default units of the measurement fields.
+	MetricUnits map[string]string
+
+	// DropUnnamedMeasurements drops metrics which have fields but no measurement instead of
+	// naming them after the input.
+	DropUnnamedMeasurements bool
+
+	// DropUntaggedMetrics drops metrics which have no tags left once the tag options are applied. Otherwise, their
+	// data points are emitted with empty attributes.
+	DropUntaggedMetrics bool
+
+	// ClampValues clamps float values outside the supported range to the nearest bound instead of
+	// letting the destination reject them. The bounds apply to the absolute value and default to
+	// the range accepted by CloudWatch when unset.
+	ClampValues       bool
+	ClampMaxValue     float64
+	ClampMinMagnitude float64
+
+	// MergeHistogramsAcrossAttribute maps a histogram metric name to an attribute which is collapsed by
+	// merging the distributions of every value of the attribute. The merged histograms are emitted on
+	// the next GetOtelMetrics.
+	MergeHistogramsAcrossAttribute map[string]string
+
+	// TimeFieldsAsEpoch converts time.Time fields (e.g. last_seen) to gauges of the epoch seconds
+	// instead of dropping them.
+	TimeFieldsAsEpoch bool
+
+	// DropOutOfOrder drops points which are older than the latest point already seen for the same series.
+	DropOutOfOrder bool
+	// MaxOutOfOrderSeries caps the number of series whose latest timestamp is remembered, evicting the least
+	// recently seen series. It defaults to 10000.
+	MaxOutOfOrderSeries int
+
+	// GroupByResource merges the metrics sharing an identical resource into a single ResourceMetrics
+	// per flush instead of a ResourceMetrics per Telegraf metric.
+	GroupByResource bool
+	// HoistCommonAttributes moves the data point attributes shared with the same value by all the data points of a
+	// resource to the resource, when it has several data points. It is mostly useful with GroupByResource. The
+	// existing resource attributes are not overwritten.
+	HoistCommonAttributes bool
+
+	// NegativeCounterPolicy handles negative values of counter fields. Negative values are emitted as
+	// is when unset.
+	NegativeCounterPolicy NegativeCounterPolicy
+
+	// HistogramBucketFieldSuffix reconstructs Prometheus style histograms, which Telegraf splits into a metric
+	// per bucket, when set (e.g. _bucket). Histogram fields with the suffix and an le tag are the cumulative
+	// bucket counts, and the _count and _sum fields of the same base name are the count and sum. The le="+Inf"
+	// bucket is the implicit overflow bucket. The histograms are emitted on the next GetOtelMetrics.
+	HistogramBucketFieldSuffix string
+
+	// IncludeInstanceMetadata stamps the metadata from the MetadataProvider as resource attributes, without
+	// overwriting the existing ones. The metadata is cached for MetadataTTL, which defaults to an hour.
+	IncludeInstanceMetadata bool
+	MetadataProvider        MetadataProvider
+	MetadataTTL             time.Duration
+
+	// DropZeroFields lists the fields which are dropped when their value is zero. The other fields of
+	// the metric are kept.
+	DropZeroFields []string
+
+	// AllowFields lists the only fields which are converted when it is not empty. The other fields of the
+	// metrics are dropped.
+	AllowFields map[string]struct{}
+
+	// HistogramFractionalCount adds the sum of the sample weights of each distribution as the sample_count
+	// attribute of the histogram, since the data point count cannot hold fractional weights.
+	HistogramFractionalCount bool
+
+	// TrimAttributeValues removes the leading and trailing whitespace of the attribute values.
+	TrimAttributeValues bool
+
+	// DefaultUnit is the unit of the metrics whose unit is neither configured nor known (e.g. None).
+	DefaultUnit string
+
+	// IntCountersAsInt keeps integer counter fields as integer data points and only converts the unsigned
+	// values beyond the int64 range to doubles, instead of letting them wrap around. It takes precedence over
+	// StrictUintOverflow for the counters, so they are not dropped.
+	IntCountersAsInt bool
+
+	// ResourceTags lists the tags promoted to resource attributes instead of data point attributes.
+	ResourceTags []string
+	// ResourceAttributes are static attributes (e.g. deployment environment, cluster name) added to every resource.
+	// The existing resource attributes take precedence.
+	ResourceAttributes map[string]string
+	// ResourceProvider returns the attributes (e.g. cluster name known after the agent started) added to every new
+	// resource. It is evaluated for each resource, so the attributes reflect its latest value. The existing resource
+	// attributes take precedence, and the provided attributes over the ResourceAttributes.
+	ResourceProvider func() pcommon.Map
+	// ResourceTagTransform maps a promoted tag to a function transforming its value into the resource
+	// attribute value (e.g. the region from an availability zone).
+	ResourceTagTransform map[string]func(string) string
+
+	// MaxExplicitBounds caps the number of explicit bounds of the histograms built from distributions by
+	// merging adjacent buckets when exceeded. The bounds are not capped when unset.
+	MaxExplicitBounds int
+	// BucketMergeStrategy decides which adjacent buckets are merged first to cap the explicit bounds. It defaults
+	// to merging uniformly across the whole range.
+	BucketMergeStrategy BucketMergeStrategy
+
+	// TypeResolver decides the value type of untyped metrics (e.g. from AddFields) from the measurement name
+	// and tags. Returning a type other than a counter or gauge keeps the metric untyped.
+	TypeResolver func(name string, tags map[string]string) telegraf.ValueType
+
+	// InferSumFromName converts the untyped fields with the _total suffix (e.g. requests_total) into monotonic sums
+	// instead of gauges. The types from TypeResolver, TypeOverrideByTag and ForceGaugeNames take precedence.
+	InferSumFromName bool
+
+	// PruneConstantAttributes lists key=value pairs (e.g. region=us-east-1) of attributes which are removed
+	// when they are equal to the constant, since they add no value to queries but use a dimension.
+	PruneConstantAttributes []string
+
+	// DenyMetricPatterns lists globs (e.g. *_debug) of the metric names which are dropped before emission.
+	DenyMetricPatterns []string
+
+	// AttributeRename maps attribute keys to the keys they are renamed to (e.g. host to hostname).
+	AttributeRename map[string]string
+
+	// EMFHostDimension renames the host tag to the dimension name the EMF exporter expects for the host (e.g.
+	// InstanceId), after the AttributeRename renames. An existing tag of the same name is replaced.
+	EMFHostDimension string
+
+	// HistogramBounds are the explicit bounds the distributions are bucketed into instead of a bucket per
+	// distinct value. HistogramBoundsByName overrides them for a histogram metric name (e.g. latency vs size).
+	HistogramBounds       []float64
+	HistogramBoundsByName map[string][]float64
+
+	// OnResourceBuilt is called with each resource once built and the tags of the Telegraf metric, so the
+	// caller can inspect or modify the resource (e.g. add entity attributes derived from the tags).
+	OnResourceBuilt func(resource pcommon.Resource, tags map[string]string)
+
+	// EmitTimestampOnly keeps the series of a measurement and tags alive when the input emits the same
+	// measurement and tags without fields, by emitting a point flagged with no recorded value at the new
+	// timestamp for each series previously emitted.
+	EmitTimestampOnly bool
+	// MaxTimestampOnlySeries caps the number of measurements and tags whose last metric is remembered for
+	// EmitTimestampOnly, evicting the least recently seen. It defaults to 10000.
+	MaxTimestampOnlySeries int
+
+	// MaxAttributeKeyLength truncates the attribute keys longer than the limit (e.g. the dimension name limit
+	// of CloudWatch). The truncated keys end with a hash of the full key to avoid collisions.
+	MaxAttributeKeyLength int
+
+	// GaugeAggregationByField maps a gauge field name to the aggregation of its values over the interval (e.g. Mean
+	// for noisy gauges). The aggregated gauges are emitted on the next GetOtelMetrics. Gauges are emitted as is
+	// when unset or Last.
+	GaugeAggregationByField map[string]GaugeAggregation
+
+	// ReassembleSummaryByQuantileTag is the tag holding the quantile (e.g. 0.99) of the metrics split per quantile
+	// of a summary. The metrics of the same series are emitted as a single summary with all their quantiles on the
+	// next GetOtelMetrics, without the tag. The metrics are converted as is when unset.
+	ReassembleSummaryByQuantileTag string
+
+	// EmitPerMetricDropCount adds the number of fields of a metric which could not be converted (e.g. strings)
+	// as the dropped_field_count attribute of its data points.
+	EmitPerMetricDropCount bool
+
+	// HashAttributeValues lists the attributes whose values are replaced with a prefix of their SHA-256 hex
+	// digest, since they may carry sensitive values (e.g. user names or IP addresses).
+	HashAttributeValues []string
+
+	// TimestampField maps a measurement to a field holding the event time of the metric as epoch seconds
+	// (e.g. event: ts). The event time is used as the data point timestamp instead of the metric time and the
+	// field is not emitted.
+	TimestampField map[string]string
+
+	// TimestampOffset maps a measurement to a duration added to the timestamps of its data points (e.g. 5s for
+	// an input with a known clock skew).
+	TimestampOffset map[string]time.Duration
+
+	// EmitCounterRate emits the per second rate of each counter field since its previous value as an additional
+	// gauge suffixed with _rate. The rate is skipped for the interval in which the counter is reset.
+	EmitCounterRate bool
+	// MaxCounterRateSeries caps the number of counter series whose last value is remembered, evicting the least
+	// recently seen series. It defaults to 10000.
+	MaxCounterRateSeries int
+
+	// AttributeValueMap maps an attribute key to a table of source values and the canonical values they are
+	// replaced with (e.g. os: {linux: Linux}). Unmapped values are kept.
+	AttributeValueMap map[string]map[string]string
+
+	// FieldSuffixToAttribute maps a glob of a field name suffix (e.g. _p*) to the attribute the suffix is converted
+	// into. Matching fields are emitted as their base metric with the suffix, without the separator, as the
+	// attribute value (e.g. latency_p50 as latency with quantile=p50). The longest matching suffix is used, and the
+	// first pattern in lexical order among the patterns matching it.
+	FieldSuffixToAttribute map[string]string
+
+	// OnAfterDrain is called by GetOtelMetrics with the number of data points drained from the accumulator
+	// (e.g. to log the size of each flush).
+	OnAfterDrain func(count int)
+
+	// DedupeHistograms drops the histograms identical, in identity and distribution stats, to a histogram already
+	// added since the last GetOtelMetrics.
+	DedupeHistograms bool
+
+	// StableNumericType emits the values of a metric with the type of its first value, so the type of its data
+	// points does not flap. The integer values of a metric first seen as a double are converted to doubles, and
+	// the double values of a metric first seen as an integer are rounded, unless they do not fit in an int64.
+	StableNumericType bool
+	// MaxStableNumericTypeMetrics caps the number of metric names whose first type is remembered, evicting the
+	// least recently seen. It defaults to 10000.
+	MaxStableNumericTypeMetrics int
+
+	// IntMetricNames lists the metric names which are always emitted as int data points (e.g. for CloudWatch alarm
+	// thresholds), rounding their double values. The values beyond the int64 range are kept as doubles. It takes
+	// precedence over StableNumericType.
+	IntMetricNames []string
+
+	// Transformers are run in order on each metric before it is converted. Each transformer can mutate or drop the
+	// metric, in which case the following transformers are skipped.
+	Transformers []MetricTransformer
+
+	// HeartbeatMetricName is the name of a gauge with a value of 1 at the current time appended on every
+	// GetOtelMetrics, even when nothing was gathered, to show the input is alive. It is not emitted when unset.
+	HeartbeatMetricName string
+
+	// ProcessAttributes are added to every data point to identify the agent process emitting them (e.g. agent_id)
+	// in multi-agent deployments. The tags of the metrics take precedence.
+	ProcessAttributes map[string]string
+
+	// StickyMetricNames lists the metric names whose series, once seen, emit their last known value at the current
+	// time on every GetOtelMetrics without a new value, until cleared with ClearStickyMetrics (e.g. for alarms).
+	StickyMetricNames []string
+
+	// FieldValidator validates the numeric value of each field of a measurement. The fields for which it returns
+	// an error are dropped and the error is reported with AddError.
+	FieldValidator func(name, field string, value float64) error
+
+	// StampScrapeID adds a random id, generated for each GetOtelMetrics, as the scrape_id attribute of all the data
+	// points of the flush to correlate the metrics of a collection cycle.
+	StampScrapeID bool
+
+	// TypeOverrideByTag maps a tag key and value to the value type of the metrics with the tag (e.g. role: {counter:
+	// Counter}), overriding the type of counters, gauges and untyped metrics. Other types are ignored.
+	TypeOverrideByTag map[string]map[string]telegraf.ValueType
+
+	// StampDataPointIndex adds the order in which each field of a metric was converted, starting at 0, as the index
+	// attribute of its data points for cardinality analysis.
+	StampDataPointIndex bool
+
+	// MeasurementScope maps a measurement to the name of the instrumentation scope its metrics are emitted under
+	// (e.g. cpu to system) to organize the metrics by subsystem.
+	MeasurementScope map[string]string
+	// ScopeName and ScopeVersion identify the instrumentation scope of the metrics of the other measurements
+	// (e.g. the name of the input).
+	ScopeName    string
+	ScopeVersion string
+
+	// StringFieldsAsAttributes adds the non-empty string fields of a metric as attributes of the data points of its
+	// numeric fields instead of dropping them. The tags take precedence over the string fields of the same name.
+	StringFieldsAsAttributes bool
+
+	// BoolMapping decides whether true is converted to 1 and false to 0, the default, or the inverse.
+	BoolMapping BoolMapping
+	// BoolFieldSuffix is appended to the name of the bool fields (e.g. _bool) so they are emitted as separate
+	// metrics from the numeric fields of the same name.
+	BoolFieldSuffix string
+
+	// HistogramUseAccumulatorClock timestamps the histograms from AddHistogram with the Clock instead of the passed
+	// time, for reproducible timestamps.
+	HistogramUseAccumulatorClock bool
+
+	// ForceGaugeNames lists the metric names which are always emitted as gauges, even when the metric is a counter
+	// or made one by TypeResolver or TypeOverrideByTag.
+	ForceGaugeNames []string
+
+	// UnitFromFieldSuffix sets the unit of the metrics without a configured or default unit from the unit suffix of
+	// their field (e.g. Seconds for request_duration_seconds or Bytes for heap_bytes).
+	UnitFromFieldSuffix bool
+
+	// AttributesByMetric maps a glob of metric names (e.g. mysql_*) to attributes added to the data points of the
+	// matching metrics only. The existing attributes take precedence.
+	AttributesByMetric map[string]map[string]string
+
+	// CounterTemporality is the aggregation temporality of the sums converted from counters (e.g. delta for
+	// ephemeral workloads). It defaults to cumulative.
+	CounterTemporality pmetric.AggregationTemporality
+
+	// LowercaseAttributeKeys lowercases the tag keys so the dimensions do not differ only by case. When keys
+	// collide (e.g. Host and host), the last one in key order wins.
+	LowercaseAttributeKeys bool
+
+	// NonMonotonicCounters marks the sums converted from counters as non-monotonic. Counters are monotonic by
+	// default.
+	NonMonotonicCounters bool
+
+	// CounterMonotonicByMeasurement overrides if the sums converted from the counters of a measurement are
+	// monotonic (e.g. false for known resettable counters).
+	CounterMonotonicByMeasurement map[string]bool
+
+	// SumBehaviorByName sets both the monotonicity and the aggregation temporality of the sums converted from
+	// counters by metric name. It takes precedence over the other counter options.
+	SumBehaviorByName map[string]SumBehavior
+
+	// CounterStartTimestamp sets the start timestamp of the cumulative sums to the first time their series was
+	// seen, or to the time the counter was last reset (i.e. decreased), so rates can be computed across restarts.
+	CounterStartTimestamp bool
+	// MaxCounterStartSeries caps the number of series whose start time is remembered. It defaults to 10000.
+	MaxCounterStartSeries int
+
+	// MaxAttributes caps the number of attributes of each data point (e.g. 30 for the CloudWatch dimension limit)
+	// after all the attributes were added. The injected default attributes are dropped before the tags.
+	MaxAttributes int
+
+	// FieldRenameRegex renames the fields in order before they are joined with the measurement into the metric
+	// name (e.g. ^usage_ to an empty replacement converts cpu usage_idle into cpu_idle).
+	FieldRenameRegex []FieldRename
+
+	// HistogramPercentiles lists the percentiles (e.g. 50, 90, 99) of the distributions added as attributes of their
+	// histogram data points (e.g. p50, p90, p99).
+	HistogramPercentiles []float64
+	// EmitPercentilesAsGauges lists the percentiles of the distributions additionally emitted as gauges named
+	// after the histogram (e.g. name_p50, name_p99).
+	EmitPercentilesAsGauges []float64
+
+	// NameSanitizer builds the metric names from the measurement and the field joined with a space, so the names do
+	// not depend on the OS (e.g. SanitizeUnderscore). The measurement and field are joined with an underscore, or a
+	// space on Windows, when nil.
+	NameSanitizer func(string) string
+	// NameJoiner combines the measurement and the field into the metric name (e.g. JoinWithSeparator(".")), before
+	// the NameSanitizer is applied to the result. The measurement and field are joined as described for the
+	// NameSanitizer when nil.
+	NameJoiner func(measurement, field string) string
+
+	// EmitFieldTotal maps a measurement to the name of a field added with the sum of its numeric fields (e.g. total
+	// for the bytes of the rx and tx fields). An existing field of the same name is kept.
+	EmitFieldTotal map[string]string
+
+	// CollisionResolution decides which value is kept when several source metrics end up in the same series
+	// within one flush (e.g. after renames). All the values are kept when empty.
+	CollisionResolution CollisionResolution
+
+	// ErrorRateLimitWindow and MaxErrorsPerWindow limit the identical consecutive errors logged by AddError within
+	// the window (e.g. at most 1 per minute for a flapping input). The errors are not limited when the window is
+	// not positive.
+	ErrorRateLimitWindow time.Duration
+	MaxErrorsPerWindow   int
+
+	// RecordInputs keeps the Telegraf metrics received since the last GetOtelMetrics to be serialized with
+	// DumpInputs and replayed with ReplayInputs, e.g. to reproduce a production issue in a test.
+	RecordInputs bool
+
+	// AllowNonFiniteValues passes the NaN and infinite field values through instead of dropping the fields, for
+	// destinations which support them.
+	AllowNonFiniteValues bool
+
+	// StrictUintOverflow drops the unsigned fields beyond the int64 range with an error instead of converting
+	// them to doubles, which loses precision.
+	StrictUintOverflow bool
+
+	// EstimateCardinality keeps a HyperLogLog sketch of the series of each metric name, whose estimated number of
+	// distinct series is returned by EstimatedCardinality. Each sketch uses 4KB.
+	EstimateCardinality bool
+
+	// PrecisionByMetric maps a metric (i.e. the Telegraf measurement) to the precision its timestamps are rounded to,
+	// instead of the precision of the accumulator.
+	PrecisionByMetric map[string]time.Duration
+
+	// Clock returns the current time. It defaults to time.Now.
+	Clock func() time.Time
+}
@@ -3,6 +3,10 @@
 
 package accumulator
 
+import (
+	"strings"
+)
+
 // Default unit for telegraf metrics based on the measurement and the
 // field name
 var defaultUnits = map[string]map[string]string{
@@ -118,6 +122,47 @@ var defaultUnits = map[string]map[string]string{
 	},
 }
 
+// getUnit resolves the unit of the metric built from the measurement and field. A unit configured for
+// the metric name takes precedence over the default unit of the field, then over the unit of the field
+// suffix with UnitFromFieldSuffix. The configured DefaultUnit is used when none resolves a unit.
+func getUnit(cfg *Config, measurement, fieldKey, metricName string) string {
+	if unit, ok := cfg.MetricUnits[metricName]; ok {
+		return unit
+	}
+	if unit := getDefaultUnit(measurement, fieldKey); unit != "" {
+		return unit
+	}
+	if cfg.UnitFromFieldSuffix {
+		if unit := getSuffixUnit(fieldKey); unit != "" {
+			return unit
+		}
+	}
+	return cfg.DefaultUnit
+}
+
+// suffixUnits maps the field name suffixes following the unit naming conventions (e.g. Prometheus) to their unit.
+var suffixUnits = []struct {
+	suffix string
+	unit   string
+}{
+	{"_seconds", "Seconds"},
+	{"_milliseconds", "Milliseconds"},
+	{"_ms", "Milliseconds"},
+	{"_microseconds", "Microseconds"},
+	{"_bytes", "Bytes"},
+	{"_bits", "Bits"},
+	{"_percent", "Percent"},
+}
+
+func getSuffixUnit(fieldKey string) string {
+	for _, s := range suffixUnits {
+		if strings.HasSuffix(fieldKey, s.suffix) {
+			return s.unit
+		}
+	}
+	return ""
+}
+
 func getDefaultUnit(measurement string, fieldKey string) string {
 	supportedFieldsUnit, ok := defaultUnits[measurement]
 	if !ok {
@@ -0,0 +1,64 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// errorLimiter tracks the identical consecutive errors of the current window to suppress the repeated ones.
+type errorLimiter struct {
+	mutex       sync.Mutex
+	last        string
+	windowStart time.Time
+	count       int
+	suppressed  int
+}
+
+// allowError checks if the error message is logged. Once MaxErrorsPerWindow identical consecutive errors were
+// logged within ErrorRateLimitWindow, the following ones are suppressed until the error changes or the window
+// ends, at which point the number of suppressed errors is logged.
+func (o *otelAccumulator) allowError(msg string) bool {
+	if o.cfg.ErrorRateLimitWindow <= 0 {
+		return true
+	}
+
+	l := &o.errorLimit
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := o.now()
+	if msg != l.last || now.Sub(l.windowStart) >= o.cfg.ErrorRateLimitWindow {
+		o.logRepeatedError()
+		l.last, l.windowStart, l.count = msg, now, 0
+	}
+	l.count++
+	if l.count <= max(o.cfg.MaxErrorsPerWindow, 1) {
+		return true
+	}
+	l.suppressed++
+	o.stats.suppressedErrors.Add(1)
+	return false
+}
+
+// flushSuppressedErrors logs the number of errors suppressed since the last log line.
+func (o *otelAccumulator) flushSuppressedErrors() {
+	l := &o.errorLimit
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	o.logRepeatedError()
+}
+
+// logRepeatedError logs the number of times the last error was suppressed, if any. The caller must hold the mutex
+// of the limiter.
+func (o *otelAccumulator) logRepeatedError() {
+	l := &o.errorLimit
+	if l.suppressed == 0 {
+		return
+	}
+	o.logger.Error("Error with adapter repeated", zap.String("error", l.last), zap.Int("times", l.suppressed))
+	l.suppressed = 0
+}
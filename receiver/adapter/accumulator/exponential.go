@@ -0,0 +1,182 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"math"
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/exponential"
+)
+
+// distributionToExponential sets the count, sum, min, max and the exponential buckets of the data point from the
+// values of the distribution. Negative values are counted in the negative buckets and zeros in the zero count. An
+// empty distribution results in a zero count data point without buckets.
+func distributionToExponential(d distribution.Distribution, dp pmetric.ExponentialHistogramDataPoint) {
+	// The exponential distributions already hold exponential buckets
+	if ed, ok := d.(*exponential.ExponentialDistribution); ok {
+		ed.ConvertToOtelExponential(dp)
+		return
+	}
+	dp.SetCount(uint64(d.SampleCount()))
+	dp.SetSum(d.Sum())
+	values, counts := d.ValuesAndCounts()
+	if len(values) == 0 {
+		return
+	}
+	dp.SetMin(d.Minimum())
+	dp.SetMax(d.Maximum())
+	populateExponentialDataPoint(dp, values, counts)
+}
+
+// populateExponentialDataPoint sets the scale and the buckets of the data point from the weighted values.
+func populateExponentialDataPoint(dp pmetric.ExponentialHistogramDataPoint, values []float64, counts []float64) {
+	scale := exponentialScale(values)
+	dp.SetScale(scale)
+	positive := map[int32]uint64{}
+	negative := map[int32]uint64{}
+	for i, value := range values {
+		// Beware of potential loss of precision due to type conversion.
+		count := uint64(counts[i])
+		switch {
+		case value > 0:
+			positive[exponential.BucketIndex(value, scale)] += count
+		case value < 0:
+			negative[exponential.BucketIndex(-value, scale)] += count
+		default:
+			dp.SetZeroCount(dp.ZeroCount() + count)
+		}
+	}
+	populateExponentialBuckets(dp.Positive(), positive)
+	populateExponentialBuckets(dp.Negative(), negative)
+}
+
+// exponentialScale returns the largest scale at which the positive and the negative values each span at most
+// DefaultMaxBuckets buckets.
+func exponentialScale(values []float64) int32 {
+	scale := exponential.MaxScale
+	for ; scale > exponential.MinScale; scale-- {
+		if exponentialSpan(values, scale, 1) <= exponential.DefaultMaxBuckets && exponentialSpan(values, scale, -1) <= exponential.DefaultMaxBuckets {
+			break
+		}
+	}
+	return scale
+}
+
+// exponentialSpan returns the number of buckets between the lowest and highest index of the values of the sign.
+func exponentialSpan(values []float64, scale int32, sign float64) int64 {
+	first, last := int32(math.MaxInt32), int32(math.MinInt32)
+	for _, value := range values {
+		if value*sign <= 0 {
+			continue
+		}
+		index := exponential.BucketIndex(value*sign, scale)
+		first, last = min(first, index), max(last, index)
+	}
+	if first > last {
+		return 0
+	}
+	return int64(last) - int64(first) + 1
+}
+
+// explicitToExponential converts the explicit buckets of src into the exponential buckets of dst at the scale,
+// bounded by MinScale and MaxScale. The count of each explicit bucket is put into the exponential bucket of its
+// midpoint, so the total count is conserved.
+func explicitToExponential(src pmetric.HistogramDataPoint, dst pmetric.ExponentialHistogramDataPoint, scale int32) {
+	scale = max(exponential.MinScale, min(exponential.MaxScale, scale))
+	dst.SetTimestamp(src.Timestamp())
+	dst.SetStartTimestamp(src.StartTimestamp())
+	dst.SetCount(src.Count())
+	dst.SetSum(src.Sum())
+	if src.HasMin() {
+		dst.SetMin(src.Min())
+	}
+	if src.HasMax() {
+		dst.SetMax(src.Max())
+	}
+	dst.SetScale(scale)
+	src.Attributes().CopyTo(dst.Attributes())
+
+	midpoints, counts := explicitMidpoints(src)
+	positive := map[int32]uint64{}
+	negative := map[int32]uint64{}
+	for i, midpoint := range midpoints {
+		switch {
+		case midpoint > 0:
+			positive[exponential.BucketIndex(midpoint, scale)] += counts[i]
+		case midpoint < 0:
+			negative[exponential.BucketIndex(-midpoint, scale)] += counts[i]
+		default:
+			dst.SetZeroCount(dst.ZeroCount() + counts[i])
+		}
+	}
+	populateExponentialBuckets(dst.Positive(), positive)
+	populateExponentialBuckets(dst.Negative(), negative)
+}
+
+// explicitScale returns the largest scale at which the midpoints of the explicit buckets fit in DefaultMaxBuckets
+// exponential buckets.
+func explicitScale(src pmetric.HistogramDataPoint) int32 {
+	midpoints, _ := explicitMidpoints(src)
+	return exponentialScale(midpoints)
+}
+
+// explicitMidpoints returns the midpoint and the count of each non-empty explicit bucket of the data point. The
+// first bucket starts at the minimum and the overflow bucket ends at the maximum when known.
+func explicitMidpoints(src pmetric.HistogramDataPoint) (midpoints []float64, counts []uint64) {
+	type bucket struct {
+		bound float64
+		count uint64
+	}
+	n := min(src.ExplicitBounds().Len(), src.BucketCounts().Len())
+	buckets := make([]bucket, n)
+	for i := 0; i < n; i++ {
+		buckets[i] = bucket{bound: src.ExplicitBounds().At(i), count: src.BucketCounts().At(i)}
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].bound < buckets[j].bound })
+	// the extra count is the overflow bucket above the last bound
+	if src.BucketCounts().Len() > n && n > 0 {
+		upper := buckets[n-1].bound
+		if src.HasMax() && src.Max() > upper {
+			upper = src.Max()
+		}
+		buckets = append(buckets, bucket{bound: upper, count: src.BucketCounts().At(n)})
+	}
+
+	for i, b := range buckets {
+		if b.count == 0 {
+			continue
+		}
+		lower := b.bound
+		switch {
+		case i > 0:
+			lower = buckets[i-1].bound
+		case src.HasMin() && src.Min() < b.bound:
+			lower = src.Min()
+		}
+		midpoints = append(midpoints, (lower+b.bound)/2)
+		counts = append(counts, b.count)
+	}
+	return midpoints, counts
+}
+
+// populateExponentialBuckets sets the offset and the dense counts of the buckets from the counts by index.
+func populateExponentialBuckets(buckets pmetric.ExponentialHistogramDataPointBuckets, counts map[int32]uint64) {
+	if len(counts) == 0 {
+		return
+	}
+	first, last := int32(math.MaxInt32), int32(math.MinInt32)
+	for index := range counts {
+		first, last = min(first, index), max(last, index)
+	}
+	dense := make([]uint64, last-first+1)
+	for index, count := range counts {
+		dense[index-first] = count
+	}
+	buckets.SetOffset(first)
+	buckets.BucketCounts().FromRaw(dense)
+}
@@ -0,0 +1,223 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/exponential"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/regular"
+)
+
+func TestExplicitToExponential(t *testing.T) {
+	as := assert.New(t)
+	src := pmetric.NewHistogramDataPoint()
+	src.SetCount(16)
+	src.SetSum(60)
+	src.SetMin(0.5)
+	src.SetMax(10)
+	src.Attributes().PutStr("path", "/")
+	// (min, 1], (1, 2], (2, 4], (4, max]
+	src.ExplicitBounds().FromRaw([]float64{2, 1, 4})
+	src.BucketCounts().FromRaw([]uint64{5, 3, 6, 2})
+
+	dst := pmetric.NewExponentialHistogramDataPoint()
+	explicitToExponential(src, dst, 0)
+
+	as.Equal(uint64(16), dst.Count())
+	as.Equal(60.0, dst.Sum())
+	as.Equal(0.5, dst.Min())
+	as.Equal(10.0, dst.Max())
+	as.Equal(int32(0), dst.Scale())
+	as.Equal(map[string]any{"path": "/"}, dst.Attributes().AsRaw())
+	// the midpoints 0.75, 1.5, 3 and 7 are in the buckets (0.5, 1], (1, 2], (2, 4] and (4, 8]
+	as.Equal(int32(-1), dst.Positive().Offset())
+	as.Equal([]uint64{3, 5, 6, 2}, dst.Positive().BucketCounts().AsRaw())
+	as.Equal(0, dst.Negative().BucketCounts().Len())
+	as.Equal(uint64(0), dst.ZeroCount())
+}
+
+func TestExplicitToExponentialConservesCount(t *testing.T) {
+	as := assert.New(t)
+	dist := regular.NewRegularDistribution()
+	for i := 0; i < 1000; i++ {
+		as.NoError(dist.AddEntry(float64(i%97)*1.5, float64(i%3+1)))
+	}
+	src := pmetric.NewHistogramDataPoint()
+	dist.ConvertToOtel(src)
+	rebucket(src, dist, []float64{1, 10, 25, 50, 100})
+
+	for _, scale := range []int32{-2, 0, 3, 8} {
+		dst := pmetric.NewExponentialHistogramDataPoint()
+		explicitToExponential(src, dst, scale)
+
+		var total uint64
+		for _, count := range dst.Positive().BucketCounts().AsRaw() {
+			total += count
+		}
+		total += dst.ZeroCount()
+		as.Equal(src.Count(), total)
+		as.Equal(uint64(dist.SampleCount()), dst.Count())
+		as.InDelta(dist.Sum(), dst.Sum(), 1e-9)
+		// the buckets are within the range of the distribution
+		as.GreaterOrEqual(exponential.BucketIndex(dist.Maximum(), scale)+1, dst.Positive().Offset()+int32(dst.Positive().BucketCounts().Len()))
+	}
+}
+
+func TestAddHistogramAsExponential(t *testing.T) {
+	as := assert.New(t)
+	dist := regular.NewRegularDistribution()
+	as.NoError(dist.AddEntry(0, 1))
+	as.NoError(dist.AddEntry(1, 2))
+	as.NoError(dist.AddEntry(5, 3))
+	as.NoError(dist.AddEntry(1000, 1))
+
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.ExponentialHistograms = true
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{}, time.Now())
+	acc.AddHistogram("idle", map[string]interface{}{"value": regular.NewRegularDistribution()}, map[string]string{}, time.Now())
+
+	got := map[string]pmetric.ExponentialHistogramDataPoint{}
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			as.Equal(pmetric.MetricTypeExponentialHistogram, ms.At(i).Type())
+			got[ms.At(i).Name()] = ms.At(i).ExponentialHistogram().DataPoints().At(0)
+		}
+	})
+	as.Len(got, 2)
+
+	dp := got["latency"]
+	as.Equal(uint64(7), dp.Count())
+	as.Equal(1017.0, dp.Sum())
+	as.Equal(0.0, dp.Min())
+	as.Equal(1000.0, dp.Max())
+	as.Equal(uint64(1), dp.ZeroCount())
+	as.Equal(uint64(6), sumBucketCounts(dp.Positive()))
+	as.LessOrEqual(dp.Positive().BucketCounts().Len(), exponential.DefaultMaxBuckets)
+	as.Equal(0, dp.Negative().BucketCounts().Len())
+	lower := math.Pow(2, math.Pow(2, -float64(dp.Scale()))*float64(dp.Positive().Offset()))
+	as.Less(lower, 1.0)
+
+	dp = got["idle"]
+	as.Equal(uint64(0), dp.Count())
+	as.Equal(uint64(0), dp.ZeroCount())
+	as.False(dp.HasMin())
+	as.Equal(0, dp.Positive().BucketCounts().Len())
+}
+
+func TestExplicitToExponentialClampsScale(t *testing.T) {
+	as := assert.New(t)
+	src := pmetric.NewHistogramDataPoint()
+	src.SetCount(3)
+	src.ExplicitBounds().FromRaw([]float64{1, 2})
+	src.BucketCounts().FromRaw([]uint64{1, 2, 0})
+
+	for scale, want := range map[int32]int32{100: exponential.MaxScale, -100: exponential.MinScale, 4: 4} {
+		dst := pmetric.NewExponentialHistogramDataPoint()
+		explicitToExponential(src, dst, scale)
+		as.Equal(want, dst.Scale())
+		as.Equal(uint64(3), sumBucketCounts(dst.Positive()))
+	}
+}
+
+func TestAddHistogramAsExponentialFromExplicitBuckets(t *testing.T) {
+	as := assert.New(t)
+	dist := regular.NewRegularDistribution()
+	as.NoError(dist.AddEntry(0.5, 2))
+	as.NoError(dist.AddEntry(5, 3))
+	as.NoError(dist.AddEntry(50, 1))
+	as.NoError(dist.AddEntry(500, 1))
+
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.ExponentialHistograms = true
+	acc.cfg.ExponentialFromExplicitBuckets = true
+	acc.cfg.HistogramBounds = []float64{1, 10, 100}
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{"path": "/"}, time.Now())
+
+	var dps []pmetric.ExponentialHistogramDataPoint
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			as.Equal(pmetric.MetricTypeExponentialHistogram, ms.At(i).Type())
+			dps = append(dps, ms.At(i).ExponentialHistogram().DataPoints().At(0))
+		}
+	})
+	if !as.Len(dps, 1) {
+		return
+	}
+	dp := dps[0]
+	as.Equal(uint64(7), dp.Count())
+	as.Equal(566.0, dp.Sum())
+	as.Equal(0.5, dp.Min())
+	as.Equal(500.0, dp.Max())
+	as.Equal(map[string]any{"path": "/"}, dp.Attributes().AsRaw())
+	as.LessOrEqual(dp.Scale(), exponential.MaxScale)
+	as.LessOrEqual(dp.Positive().BucketCounts().Len(), exponential.DefaultMaxBuckets)
+	as.Equal(uint64(7), sumBucketCounts(dp.Positive()))
+	// the counts of the explicit buckets (0.5, 1], (1, 10], (10, 100] and (100, 500] are at their midpoints
+	for midpoint, count := range map[float64]uint64{0.75: 2, 5.5: 3, 55: 1, 300: 1} {
+		index := exponential.BucketIndex(midpoint, dp.Scale()) - dp.Positive().Offset()
+		as.Equal(count, dp.Positive().BucketCounts().At(int(index)), "midpoint=%v", midpoint)
+	}
+}
+
+func TestAddHistogramWithExponentialDistribution(t *testing.T) {
+	dist := exponential.NewExponentialDistributionWithScale(0, 160)
+	for _, value := range []float64{0, 3, 4, 10} {
+		assert.NoError(t, dist.AddEntry(value, 2))
+	}
+	for _, asExponential := range []bool{false, true} {
+		as := assert.New(t)
+		acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+		acc.cfg.ExponentialHistograms = asExponential
+		acc.cfg.HistogramPercentiles = []float64{50}
+
+		acc.AddHistogram("latency", map[string]interface{}{"get": dist}, map[string]string{}, time.Now())
+
+		m := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+		if asExponential {
+			dp := m.ExponentialHistogram().DataPoints().At(0)
+			as.Equal(uint64(8), dp.Count())
+			as.Equal(34.0, dp.Sum())
+			as.Equal(uint64(2), dp.ZeroCount())
+			as.Equal(int32(1), dp.Positive().Offset())
+			as.Equal([]uint64{4, 0, 2}, dp.Positive().BucketCounts().AsRaw())
+			// the median is the geometric middle of the (2, 4] bucket
+			p50, _ := dp.Attributes().Get("p50")
+			as.InDelta(math.Sqrt(8), p50.Double(), 1e-9)
+		} else {
+			dp := m.Histogram().DataPoints().At(0)
+			as.Equal(uint64(8), dp.Count())
+			as.Equal([]float64{0, 4, 10}, dp.ExplicitBounds().AsRaw())
+			as.Equal([]uint64{2, 4, 2, 0}, dp.BucketCounts().AsRaw())
+			// the median is the geometric middle of the (2, 4] bucket
+			p50, _ := dp.Attributes().Get("p50")
+			as.InDelta(math.Sqrt(8), p50.Double(), 1e-9)
+		}
+	}
+}
+
+func TestPopulateExponentialDataPointNegative(t *testing.T) {
+	as := assert.New(t)
+	dp := pmetric.NewExponentialHistogramDataPoint()
+	populateExponentialDataPoint(dp, []float64{-4, -2, 0, 3}, []float64{1, 2, 3, 4})
+
+	as.Equal(uint64(3), sumBucketCounts(dp.Negative()))
+	as.Equal(uint64(3), dp.ZeroCount())
+	as.Equal(uint64(4), sumBucketCounts(dp.Positive()))
+	as.Equal(exponential.BucketIndex(2, dp.Scale()), dp.Negative().Offset())
+	as.Equal(exponential.BucketIndex(3, dp.Scale()), dp.Positive().Offset())
+}
+
+func sumBucketCounts(buckets pmetric.ExponentialHistogramDataPointBuckets) uint64 {
+	var total uint64
+	for _, count := range buckets.BucketCounts().AsRaw() {
+		total += count
+	}
+	return total
+}
@@ -0,0 +1,280 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"go.uber.org/zap"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/util"
+)
+
+const (
+	// defaultClampMaxValue and defaultClampMinMagnitude are the range of absolute values accepted by
+	// some CloudWatch APIs.
+	defaultClampMaxValue     = 1.174271e+38
+	defaultClampMinMagnitude = 8.515920e-39
+
+	// droppedFieldCountAttribute is the attribute holding the number of fields of the metric which could
+	// not be converted.
+	droppedFieldCountAttribute = "dropped_field_count"
+)
+
+// BoolMapping decides the values bool fields are converted to.
+type BoolMapping string
+
+const (
+	// BoolTrueIsOne converts true to 1 and false to 0.
+	BoolTrueIsOne BoolMapping = "TrueIsOne"
+	// BoolTrueIsZero converts true to 0 and false to 1 (e.g. for a healthy flag alarming on 1).
+	BoolTrueIsZero BoolMapping = "TrueIsZero"
+)
+
+// toOtelValue converts the field value to a value supported by OTEL (int64, float64 or a distribution)
+// after applying the field related options from the Config.
+func (o *otelAccumulator) toOtelValue(m telegraf.Metric, field string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		if o.cfg.BoolMapping == BoolTrueIsZero {
+			v = !v
+		}
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		if mapped, ok := o.cfg.EnumMappings[field][v]; ok {
+			return mapped, nil
+		}
+	case time.Time:
+		if o.cfg.TimeFieldsAsEpoch {
+			return float64(v.UnixNano()) / float64(time.Second), nil
+		}
+	case float64:
+		if o.cfg.AllowNonFiniteValues && (math.IsNaN(v) || math.IsInf(v, 0)) {
+			return v, nil
+		}
+	case uint64:
+		if v > math.MaxInt64 {
+			return o.uintOverflow(m, v)
+		}
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return o.uintOverflow(m, uint64(v))
+		}
+	}
+	otelValue, err := util.ToOtelValue(value)
+	if f, ok := otelValue.(float64); ok && o.cfg.ClampValues {
+		otelValue = o.clamp(f)
+	}
+	return otelValue, err
+}
+
+// uintOverflow converts the unsigned value beyond the int64 range to a double, so it does not wrap around to a
+// negative int, or rejects it when StrictUintOverflow is set. The counters are always converted with
+// IntCountersAsInt.
+func (o *otelAccumulator) uintOverflow(m telegraf.Metric, v uint64) (interface{}, error) {
+	if o.cfg.IntCountersAsInt && m.Type() == telegraf.Counter {
+		return float64(v), nil
+	}
+	if o.cfg.StrictUintOverflow {
+		return nil, fmt.Errorf("unsigned value overflows int64: %d", v)
+	}
+	return float64(v), nil
+}
+
+// clamp limits the absolute value of v to the configured range while keeping its sign. Zero is always supported.
+func (o *otelAccumulator) clamp(v float64) float64 {
+	maxValue, minMagnitude := o.cfg.ClampMaxValue, o.cfg.ClampMinMagnitude
+	if maxValue == 0 {
+		maxValue = defaultClampMaxValue
+	}
+	if minMagnitude == 0 {
+		minMagnitude = defaultClampMinMagnitude
+	}
+
+	abs := math.Abs(v)
+	switch {
+	case abs > maxValue:
+		abs = maxValue
+	case abs != 0 && abs < minMagnitude:
+		abs = minMagnitude
+	default:
+		return v
+	}
+	o.stats.clampedValues.Add(1)
+	return math.Copysign(abs, v)
+}
+
+// isDroppedZeroField checks if the field is listed in DropZeroFields and its converted value is zero.
+func (o *otelAccumulator) isDroppedZeroField(field string, otelValue interface{}) bool {
+	if !slices.Contains(o.cfg.DropZeroFields, field) {
+		return false
+	}
+	switch v := otelValue.(type) {
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	}
+	return false
+}
+
+// dropDisallowedFields removes the fields not listed in AllowFields. All the fields are kept when AllowFields is
+// empty.
+func (o *otelAccumulator) dropDisallowedFields(m telegraf.Metric) {
+	if len(o.cfg.AllowFields) == 0 {
+		return
+	}
+	for field := range m.Fields() {
+		if _, ok := o.cfg.AllowFields[field]; !ok {
+			m.RemoveField(field)
+		}
+	}
+}
+
+// addFieldTotal adds the field configured in EmitFieldTotal for the measurement with the sum of the converted
+// fields (e.g. total for the rx and tx of the network bytes). The total is an integer when all the fields are.
+func (o *otelAccumulator) addFieldTotal(m telegraf.Metric) {
+	field, ok := o.cfg.EmitFieldTotal[m.Name()]
+	if !ok || m.HasField(field) {
+		return
+	}
+	var intTotal int64
+	var floatTotal float64
+	isFloat := false
+	for _, value := range m.Fields() {
+		switch v := value.(type) {
+		case int64:
+			intTotal += v
+		case float64:
+			floatTotal += v
+			isFloat = true
+		}
+	}
+	if isFloat {
+		m.AddField(field, floatTotal+float64(intTotal))
+	} else {
+		m.AddField(field, intTotal)
+	}
+}
+
+// applyTimestampField replaces the time of the metric with the event time held by the field configured in
+// TimestampField for its measurement, as epoch seconds. The field is removed so it is not emitted as a metric.
+func (o *otelAccumulator) applyTimestampField(m telegraf.Metric) {
+	field, ok := o.cfg.TimestampField[m.Name()]
+	if !ok {
+		return
+	}
+	value, ok := m.GetField(field)
+	if !ok {
+		return
+	}
+	m.RemoveField(field)
+	seconds, ok := toFloat64(value)
+	if !ok || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		o.logger.Debug("Ignored invalid timestamp field", zap.String("name", m.Name()), zap.String("field", field), zap.Any("value", value))
+		return
+	}
+	whole, frac := math.Modf(seconds)
+	m.SetTime(time.Unix(int64(whole), int64(frac*float64(time.Second))).Round(o.precisionFor(m.Name())))
+}
+
+// applyTimestampOffset shifts the time of the metric by the offset configured in TimestampOffset for its
+// measurement, to correct the inputs with a known clock skew.
+func (o *otelAccumulator) applyTimestampOffset(m telegraf.Metric) {
+	if offset, ok := o.cfg.TimestampOffset[m.Name()]; ok {
+		m.SetTime(m.Time().Add(offset))
+	}
+}
+
+// fieldGroup holds the fields of a metric converted with the same tags.
+type fieldGroup struct {
+	fields map[string]interface{}
+	tags   map[string]string
+}
+
+// splitFieldSuffixes groups the fields of the metric by the attribute values encoded in their suffix, as configured
+// in FieldSuffixToAttribute. e.g. the suffix pattern _p* for the quantile attribute converts latency_p50 into the
+// latency field with quantile=p50. The fields without a configured suffix are grouped with the tags of the metric.
+func (o *otelAccumulator) splitFieldSuffixes(m telegraf.Metric) []fieldGroup {
+	if len(o.cfg.FieldSuffixToAttribute) == 0 {
+		return []fieldGroup{{fields: m.Fields(), tags: m.Tags()}}
+	}
+
+	groups := map[string]*fieldGroup{}
+	var keys []string
+	for field, value := range m.Fields() {
+		base, attribute, attributeValue := o.matchFieldSuffix(field)
+		key := attribute + "\x00" + attributeValue
+		group, ok := groups[key]
+		if !ok {
+			group = &fieldGroup{fields: map[string]interface{}{}, tags: m.Tags()}
+			if attribute != "" {
+				group.tags[attribute] = attributeValue
+			}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		group.fields[base] = value
+	}
+	sort.Strings(keys)
+	result := make([]fieldGroup, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *groups[key])
+	}
+	return result
+}
+
+// matchFieldSuffix splits the field into its base name and the attribute encoded in the longest suffix matching
+// one of the patterns of FieldSuffixToAttribute. When several patterns match the same suffix, the first pattern in
+// lexical order wins, so the attribute does not depend on the map iteration order. The field is returned as is
+// without an attribute when no suffix matches.
+func (o *otelAccumulator) matchFieldSuffix(field string) (base string, attribute string, value string) {
+	if len(o.cfg.FieldSuffixToAttribute) == 0 {
+		return field, "", ""
+	}
+	patterns := make([]string, 0, len(o.cfg.FieldSuffixToAttribute))
+	for pattern := range o.cfg.FieldSuffixToAttribute {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for i := 1; i < len(field); i++ {
+		if field[i] != '_' {
+			continue
+		}
+		for _, pattern := range patterns {
+			g, err := compileGlob(pattern)
+			if err != nil {
+				o.logger.Debug("Ignored invalid field suffix pattern", zap.String("pattern", pattern), zap.Error(err))
+				continue
+			}
+			if g.Match(field[i:]) {
+				return field[:i], o.cfg.FieldSuffixToAttribute[pattern], field[i+1:]
+			}
+		}
+	}
+	return field, "", ""
+}
+
+// validateField runs the FieldValidator on the converted numeric value of the field.
+func (o *otelAccumulator) validateField(m telegraf.Metric, field string, otelValue interface{}) error {
+	if o.cfg.FieldValidator == nil {
+		return nil
+	}
+	value, ok := toFloat64(otelValue)
+	if !ok {
+		return nil
+	}
+	if err := o.cfg.FieldValidator(m.Name(), field, value); err != nil {
+		return fmt.Errorf("invalid field (%q) of metric (%q): %w", field, m.Name(), err)
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/metric"
+)
+
+func TestEnumMappings(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EnumMappings = map[string]map[string]float64{
+		"status": {"up": 1, "down": 0},
+	}
+
+	acc.AddGauge("service", map[string]interface{}{"status": "up", "name": "redis"}, map[string]string{}, time.Now())
+	acc.AddGauge("service", map[string]interface{}{"status": "down"}, map[string]string{}, time.Now())
+	acc.AddGauge("service", map[string]interface{}{"status": "unknown"}, map[string]string{}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+	for i, want := range []float64{1, 0} {
+		metrics := otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics()
+		as.Equal(1, metrics.Len())
+		as.Equal(metric.DecorateMetricName("service", "status"), metrics.At(0).Name())
+		as.Equal(want, metrics.At(0).Gauge().DataPoints().At(0).DoubleValue())
+	}
+}
+
+func TestClampValues(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.ClampValues = true
+	acc.cfg.ClampMaxValue = 1e38
+
+	fields := map[string]interface{}{"big": 1e40, "negative": -1e40, "tiny": 1e-45, "zero": 0.0, "normal": 42.5}
+	got, err := acc.modifyMetricAndConvertToOtelValue(testutil.MustMetric("cpu", map[string]string{}, fields, time.Now(), telegraf.Gauge))
+	as.NoError(err)
+	as.Equal(map[string]interface{}{
+		"big":      1e38,
+		"negative": -1e38,
+		"tiny":     defaultClampMinMagnitude,
+		"zero":     0.0,
+		"normal":   42.5,
+	}, got.Fields())
+	as.EqualValues(3, acc.ClampedValues())
+}
+
+func TestTimeFieldsAsEpoch(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	lastSeen := time.Unix(1700000000, 500000000)
+	m := testutil.MustMetric("peer", map[string]string{}, map[string]interface{}{"last_seen": lastSeen}, time.Now(), telegraf.Gauge)
+
+	_, err := acc.modifyMetricAndConvertToOtelValue(m.Copy())
+	as.Error(err)
+
+	acc.cfg.TimeFieldsAsEpoch = true
+	got, err := acc.modifyMetricAndConvertToOtelValue(m.Copy())
+	as.NoError(err)
+	as.Equal(map[string]interface{}{"last_seen": 1700000000.5}, got.Fields())
+}
+
+func TestDropZeroFields(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.DropZeroFields = []string{"errors", "timeouts"}
+
+	fields := map[string]interface{}{"errors": 0, "timeouts": 2.5, "requests": 10, "retries": 0}
+	got, err := acc.modifyMetricAndConvertToOtelValue(testutil.MustMetric("http", map[string]string{}, fields, time.Now(), telegraf.Gauge))
+	as.NoError(err)
+	as.Equal(map[string]interface{}{"timeouts": 2.5, "requests": int64(10), "retries": int64(0)}, got.Fields())
+}
+
+func TestAllowFields(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.AllowFields = map[string]struct{}{"used": {}}
+
+	fields := map[string]interface{}{"used": 10, "free": 20, "total": 30, "used_percent": 33.3}
+	got, err := acc.modifyMetricAndConvertToOtelValue(testutil.MustMetric("disk", map[string]string{"path": "/"}, fields, time.Now(), telegraf.Gauge))
+	as.NoError(err)
+	as.Equal(map[string]interface{}{"used": int64(10)}, got.Fields())
+}
+
+func TestEmitFieldTotal(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EmitFieldTotal = map[string]string{"net": "total", "mem": "total"}
+
+	acc.AddGauge("net", map[string]interface{}{"rx": 10, "tx": 5}, map[string]string{}, time.Now())
+	acc.AddGauge("mem", map[string]interface{}{"used": 1.5, "free": int64(2)}, map[string]string{}, time.Now())
+	acc.AddGauge("cpu", map[string]interface{}{"idle": 1.0}, map[string]string{}, time.Now())
+
+	values := map[string]pmetric.NumberDataPoint{}
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			as.Equal(pmetric.MetricTypeGauge, ms.At(i).Type())
+			values[ms.At(i).Name()] = ms.At(i).Gauge().DataPoints().At(0)
+		}
+	})
+	as.Len(values, 7)
+	as.Equal(int64(15), values[metric.DecorateMetricName("net", "total")].IntValue())
+	as.Equal(3.5, values[metric.DecorateMetricName("mem", "total")].DoubleValue())
+	_, ok := values[metric.DecorateMetricName("cpu", "total")]
+	as.False(ok)
+}
+
+func TestNonFiniteValues(t *testing.T) {
+	fields := map[string]interface{}{
+		"nan":         math.NaN(),
+		"inf":         math.Inf(1),
+		"neg_inf":     math.Inf(-1),
+		"float32_inf": float32(math.Inf(1)),
+		"usage":       1.5,
+	}
+	for _, allow := range []bool{false, true} {
+		as := assert.New(t)
+		acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+		acc.cfg.AllowNonFiniteValues = allow
+
+		got, err := acc.modifyMetricAndConvertToOtelValue(testutil.MustMetric("calc", map[string]string{}, fields, time.Now(), telegraf.Gauge))
+		as.NoError(err)
+		if !allow {
+			as.Equal(map[string]interface{}{"usage": 1.5}, got.Fields())
+		} else {
+			as.Len(got.Fields(), 5)
+			as.True(math.IsNaN(got.Fields()["nan"].(float64)))
+			as.Equal(math.Inf(1), got.Fields()["float32_inf"])
+			as.Equal(math.Inf(-1), got.Fields()["neg_inf"])
+		}
+
+		// a metric with only non-finite values is not converted
+		acc.AddGauge("calc", map[string]interface{}{"nan": math.NaN()}, map[string]string{}, time.Now())
+		as.Equal(map[bool]int{false: 0, true: 1}[allow], acc.GetOtelMetrics().ResourceMetrics().Len())
+	}
+}
+
+func TestIntCountersAsInt(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.IntCountersAsInt = true
+	// The counters are converted to doubles rather than dropped
+	acc.cfg.StrictUintOverflow = true
+
+	fields := map[string]interface{}{
+		"bytes_recv":  int64(math.MaxInt64),
+		"bytes_sent":  uint64(math.MaxUint64),
+		"packets_err": uint64(7),
+	}
+	acc.AddCounter("net", fields, map[string]string{}, time.Now())
+
+	metrics := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(3, metrics.Len())
+	for i := 0; i < metrics.Len(); i++ {
+		dp := metrics.At(i).Sum().DataPoints().At(0)
+		switch metrics.At(i).Name() {
+		case metric.DecorateMetricName("net", "bytes_recv"):
+			as.Equal(pmetric.NumberDataPointValueTypeInt, dp.ValueType())
+			as.Equal(int64(math.MaxInt64), dp.IntValue())
+		case metric.DecorateMetricName("net", "bytes_sent"):
+			as.Equal(pmetric.NumberDataPointValueTypeDouble, dp.ValueType())
+			as.Equal(float64(math.MaxUint64), dp.DoubleValue())
+		case metric.DecorateMetricName("net", "packets_err"):
+			as.Equal(pmetric.NumberDataPointValueTypeInt, dp.ValueType())
+			as.Equal(int64(7), dp.IntValue())
+		}
+	}
+}
+
+func TestUintOverflow(t *testing.T) {
+	as := assert.New(t)
+	for _, strict := range []bool{false, true} {
+		acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+		acc.cfg.StrictUintOverflow = strict
+
+		fields := map[string]interface{}{
+			"bytes_recv": uint64(math.MaxInt64) + 1,
+			"bytes_sent": uint64(math.MaxInt64),
+		}
+		acc.AddGauge("net", fields, map[string]string{}, time.Now())
+
+		metrics := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+		values := map[string]pmetric.NumberDataPoint{}
+		for i := 0; i < metrics.Len(); i++ {
+			values[metrics.At(i).Name()] = metrics.At(i).Gauge().DataPoints().At(0)
+		}
+		sent := values[metric.DecorateMetricName("net", "bytes_sent")]
+		as.Equal(pmetric.NumberDataPointValueTypeInt, sent.ValueType())
+		as.Equal(int64(math.MaxInt64), sent.IntValue())
+
+		recv, ok := values[metric.DecorateMetricName("net", "bytes_recv")]
+		if strict {
+			as.False(ok)
+			continue
+		}
+		as.True(ok)
+		as.Equal(pmetric.NumberDataPointValueTypeDouble, recv.ValueType())
+		as.Equal(float64(uint64(math.MaxInt64)+1), recv.DoubleValue())
+		as.Positive(recv.DoubleValue())
+	}
+}
+
+func TestEmitPerMetricDropCount(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EmitPerMetricDropCount = true
+
+	acc.AddGauge("procstat", map[string]interface{}{"cpu_usage": 1.5, "user": "root"}, map[string]string{"pid": "1"}, time.Now())
+	acc.AddGauge("procstat", map[string]interface{}{"cpu_usage": 1.5}, map[string]string{"pid": "2"}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+	attributes := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"pid": "1", "dropped_field_count": "1"}, attributes.AsRaw())
+	attributes = otelMetrics.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"pid": "2"}, attributes.AsRaw())
+}
+
+func TestTimestampField(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.TimestampField = map[string]string{"event": "ts"}
+
+	acc.AddGauge("event", map[string]interface{}{"ts": int64(1700000000), "latency": 1.5}, map[string]string{}, time.Now())
+
+	metrics := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(1, metrics.Len())
+	as.Equal(metric.DecorateMetricName("event", "latency"), metrics.At(0).Name())
+	dp := metrics.At(0).Gauge().DataPoints().At(0)
+	as.Equal(pcommon.NewTimestampFromTime(time.Unix(1700000000, 0)), dp.Timestamp())
+}
+
+func TestTimestampOffset(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.TimestampOffset = map[string]time.Duration{"cpu": 5 * time.Second}
+
+	now := time.Now()
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, now)
+	acc.AddGauge("mem", map[string]interface{}{"used": 1.0}, map[string]string{}, now)
+
+	timestamps := map[string]pcommon.Timestamp{}
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			timestamps[ms.At(i).Name()] = ms.At(i).Gauge().DataPoints().At(0).Timestamp()
+		}
+	})
+	as.Equal(map[string]pcommon.Timestamp{
+		metric.DecorateMetricName("cpu", "usage_idle"): pcommon.NewTimestampFromTime(now.Add(5 * time.Second)),
+		metric.DecorateMetricName("mem", "used"):       pcommon.NewTimestampFromTime(now),
+	}, timestamps)
+}
+
+func TestFieldSuffixToAttribute(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.FieldSuffixToAttribute = map[string]string{"_p*": "quantile"}
+
+	fields := map[string]interface{}{"latency_p50": 1.5, "latency_p99": 9.5, "requests": int64(10)}
+	acc.AddGauge("http", fields, map[string]string{"path": "/"}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	values := map[string]float64{}
+	for i := 0; i < otelMetrics.ResourceMetrics().Len(); i++ {
+		metrics := otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics()
+		for j := 0; j < metrics.Len(); j++ {
+			dp := metrics.At(j).Gauge().DataPoints().At(0)
+			as.Equal("/", dp.Attributes().AsRaw()["path"])
+			if metrics.At(j).Name() == metric.DecorateMetricName("http", "requests") {
+				as.Equal(map[string]any{"path": "/"}, dp.Attributes().AsRaw())
+				as.Equal(int64(10), dp.IntValue())
+				continue
+			}
+			as.Equal(metric.DecorateMetricName("http", "latency"), metrics.At(j).Name())
+			quantile, ok := dp.Attributes().Get("quantile")
+			as.True(ok)
+			values[quantile.Str()] = dp.DoubleValue()
+		}
+	}
+	as.Equal(map[string]float64{"p50": 1.5, "p99": 9.5}, values)
+}
+
+func TestFieldSuffixToAttributeOverlappingPatterns(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.FieldSuffixToAttribute = map[string]string{"_p9*": "tail", "_p*": "quantile", "_x*": "other", "_*": "any"}
+
+	for i := 0; i < 20; i++ {
+		base, attribute, value := acc.matchFieldSuffix("latency_p99")
+		as.Equal("latency", base)
+		as.Equal("any", attribute)
+		as.Equal("p99", value)
+	}
+	delete(acc.cfg.FieldSuffixToAttribute, "_*")
+	for i := 0; i < 20; i++ {
+		_, attribute, _ := acc.matchFieldSuffix("latency_p99")
+		as.Equal("quantile", attribute)
+	}
+}
+
+func TestFieldValidator(t *testing.T) {
+	as := assert.New(t)
+	core, logs := observer.New(zap.ErrorLevel)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.logger = zap.New(core)
+	acc.cfg.FieldValidator = func(_, _ string, value float64) error {
+		if value > 100 {
+			return errors.New("above 100")
+		}
+		return nil
+	}
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 101.0, "usage_user": int64(20)}, map[string]string{}, time.Now())
+
+	metrics := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(1, metrics.Len())
+	as.Equal(metric.DecorateMetricName("cpu", "usage_user"), metrics.At(0).Name())
+	as.Equal(1, logs.Len())
+	as.Equal(`invalid field ("usage_idle") of metric ("cpu"): above 100`, logs.All()[0].ContextMap()["error"])
+}
+
+func TestBoolMapping(t *testing.T) {
+	testCases := map[string]struct {
+		mapping    BoolMapping
+		suffix     string
+		wantFields map[string]interface{}
+	}{
+		"Default": {
+			wantFields: map[string]interface{}{"healthy": int64(1), "degraded": int64(0), "latency": 1.5},
+		},
+		"TrueIsOne": {
+			mapping:    BoolTrueIsOne,
+			wantFields: map[string]interface{}{"healthy": int64(1), "degraded": int64(0), "latency": 1.5},
+		},
+		"TrueIsZero": {
+			mapping:    BoolTrueIsZero,
+			wantFields: map[string]interface{}{"healthy": int64(0), "degraded": int64(1), "latency": 1.5},
+		},
+		"WithSuffix": {
+			mapping:    BoolTrueIsZero,
+			suffix:     "_bool",
+			wantFields: map[string]interface{}{"healthy_bool": int64(0), "degraded_bool": int64(1), "latency": 1.5},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			as := assert.New(t)
+			acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+			acc.cfg.BoolMapping = testCase.mapping
+			acc.cfg.BoolFieldSuffix = testCase.suffix
+
+			fields := map[string]interface{}{"healthy": true, "degraded": false, "latency": 1.5}
+			got, err := acc.modifyMetricAndConvertToOtelValue(testutil.MustMetric("service", map[string]string{}, fields, time.Now(), telegraf.Gauge))
+			as.NoError(err)
+			as.Equal(testCase.wantFields, got.Fields())
+		})
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// appendHeartbeat appends the HeartbeatMetricName gauge with a value of 1 at the current time to the accumulated
+// metrics, so every flush shows the input is alive even when it gathered nothing. The caller must hold the mutex.
+func (o *otelAccumulator) appendHeartbeat() {
+	if o.cfg.HeartbeatMetricName == "" {
+		return
+	}
+	otelMetrics := pmetric.NewMetrics()
+	populate := func(cfg *Config, _ string, metrics pmetric.MetricSlice, _ map[string]interface{}, tags map[string]string, timestamp pcommon.Timestamp) {
+		m := metrics.AppendEmpty()
+		m.SetName(cfg.HeartbeatMetricName)
+		populateNumberDataPoint(cfg, m.SetEmptyGauge().DataPoints().AppendEmpty(), int64(1), tags, timestamp)
+	}
+	AddScopeMetricsIntoOtelMetrics(&o.cfg, populate, otelMetrics, "", nil, map[string]string{}, o.now())
+	o.processOtelMetrics(otelMetrics)
+	o.appendResourceMetrics(otelMetrics)
+}
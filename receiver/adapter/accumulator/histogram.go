@@ -0,0 +1,521 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/exponential"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/regular"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/seh1"
+)
+
+// mergedHistogram holds the distributions of a histogram merged across a collapsed attribute until the next flush.
+type mergedHistogram struct {
+	measurement string
+	field       string
+	tags        map[string]string
+	dist        distribution.Distribution
+	timestamp   time.Time
+}
+
+// leTag is the tag holding the upper bound of a Prometheus histogram bucket.
+const leTag = "le"
+
+// bucketHistogram collects the cumulative buckets, count and sum of a Prometheus style histogram which
+// Telegraf splits into a metric per bucket.
+type bucketHistogram struct {
+	measurement string
+	base        string
+	tags        map[string]string
+	buckets     map[float64]float64 // from the upper bound to the cumulative count
+	count       float64
+	hasCount    bool
+	sum         float64
+	timestamp   time.Time
+}
+
+// distributionStats is the part of a distribution holding its count and sum. Partial distributions which do not
+// implement distribution.Distribution (e.g. without buckets) are converted from the parts they implement.
+type distributionStats interface {
+	SampleCount() float64
+	Sum() float64
+}
+
+// distributionRange is the part of a distribution holding its min and max.
+type distributionRange interface {
+	Minimum() float64
+	Maximum() float64
+}
+
+// populateDegradedHistogram converts a partial distribution into a histogram data point without buckets, holding
+// its count and sum, and its min and max when available. Values without a valid count and sum are skipped.
+func populateDegradedHistogram(
+	cfg *Config,
+	measurement string,
+	field string,
+	metrics pmetric.MetricSlice,
+	value interface{},
+	tags map[string]string,
+	timestamp pcommon.Timestamp,
+) {
+	stats, ok := value.(distributionStats)
+	if !ok || !(stats.SampleCount() >= 0) || math.IsInf(stats.SampleCount(), 0) {
+		return
+	}
+	m := metrics.AppendEmpty()
+	name := metricName(cfg, measurement, field)
+	m.SetName(name)
+	m.SetUnit(getUnit(cfg, measurement, field, name))
+	h := m.SetEmptyHistogram().DataPoints().AppendEmpty()
+	h.SetTimestamp(timestamp)
+	h.SetCount(uint64(stats.SampleCount()))
+	h.SetSum(stats.Sum())
+	if r, ok := value.(distributionRange); ok {
+		h.SetMin(r.Minimum())
+		h.SetMax(r.Maximum())
+	}
+	addTagsToAttributes(cfg, h.Attributes(), tags)
+}
+
+// snapshotDistributions returns the fields with a copy of the regular distributions, which the input may keep adding
+// entries to while they are converted or merged until the next flush.
+func snapshotDistributions(fields map[string]interface{}) map[string]interface{} {
+	snapshot := make(map[string]interface{}, len(fields))
+	for field, value := range fields {
+		if rd, ok := value.(*regular.RegularDistribution); ok {
+			value = rd.Clone()
+		}
+		snapshot[field] = value
+	}
+	return snapshot
+}
+
+// newEmptyDistribution creates an empty distribution of the same implementation as d, since distributions
+// can only be added to distributions of the same type.
+func newEmptyDistribution(d distribution.Distribution) distribution.Distribution {
+	switch d.(type) {
+	case *seh1.SEH1Distribution:
+		return seh1.NewSEH1Distribution()
+	case *exponential.ExponentialDistribution:
+		return exponential.NewExponentialDistribution()
+	}
+	return regular.NewRegularDistribution()
+}
+
+// dropInvalidHistograms removes the distributions whose sum is NaN or infinite, since the histograms converted
+// from them would be rejected.
+func (o *otelAccumulator) dropInvalidHistograms(m telegraf.Metric) {
+	for field, value := range m.Fields() {
+		d, ok := value.(distributionStats)
+		if !ok {
+			continue
+		}
+		if sum := d.Sum(); math.IsNaN(sum) || math.IsInf(sum, 0) {
+			m.RemoveField(field)
+			o.stats.droppedInvalidHistograms.Add(1)
+			o.logger.Debug("Dropped histogram with non-finite sum", zap.String("name", m.Name()), zap.String("field", field))
+		}
+	}
+}
+
+// dropDuplicateHistograms removes the distributions identical to one already added for the same series since the
+// last GetOtelMetrics, as some inputs add the same histogram repeatedly within an interval.
+func (o *otelAccumulator) dropDuplicateHistograms(m telegraf.Metric) {
+	if !o.cfg.DedupeHistograms {
+		return
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for field, value := range m.Fields() {
+		d, ok := value.(distribution.Distribution)
+		if !ok {
+			continue
+		}
+		key := seriesKey(metricName(&o.cfg, m.Name(), field), m.Tags()) + histogramFingerprint(d)
+		if _, ok := o.seenHistograms[key]; ok {
+			m.RemoveField(field)
+			continue
+		}
+		if o.seenHistograms == nil {
+			o.seenHistograms = map[string]struct{}{}
+		}
+		o.seenHistograms[key] = struct{}{}
+	}
+}
+
+// histogramFingerprint identifies a distribution by its stats.
+func histogramFingerprint(d distribution.Distribution) string {
+	return fmt.Sprintf("\x00%v\x00%v\x00%v\x00%v\x00%d", d.SampleCount(), d.Sum(), d.Minimum(), d.Maximum(), d.Size())
+}
+
+// mergeHistograms removes the distributions configured in MergeHistogramsAcrossAttribute from the metric and
+// merges them, without the collapsed attribute, into the histograms emitted on the next GetOtelMetrics.
+func (o *otelAccumulator) mergeHistograms(m telegraf.Metric) {
+	if len(o.cfg.MergeHistogramsAcrossAttribute) == 0 {
+		return
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for field, value := range m.Fields() {
+		d, ok := value.(distribution.Distribution)
+		if !ok {
+			continue
+		}
+		name := metricName(&o.cfg, m.Name(), field)
+		collapsed, ok := o.cfg.MergeHistogramsAcrossAttribute[name]
+		if !ok {
+			continue
+		}
+
+		tags := make(map[string]string, len(m.Tags()))
+		for k, v := range m.Tags() {
+			if k != collapsed {
+				tags[k] = v
+			}
+		}
+		key := seriesKey(name, tags)
+		merged, ok := o.mergedHistograms[key]
+		if !ok {
+			merged = &mergedHistogram{
+				measurement: m.Name(),
+				field:       field,
+				tags:        tags,
+				dist:        newEmptyDistribution(d),
+			}
+			if o.mergedHistograms == nil {
+				o.mergedHistograms = map[string]*mergedHistogram{}
+			}
+			o.mergedHistograms[key] = merged
+		}
+		if d.SampleCount() > 0 {
+			merged.dist.AddDistribution(d)
+		}
+		if m.Time().After(merged.timestamp) {
+			merged.timestamp = m.Time()
+		}
+		m.RemoveField(field)
+	}
+}
+
+// flushMergedHistograms converts the merged histograms and appends them to the accumulated metrics.
+// The caller must hold the mutex.
+func (o *otelAccumulator) flushMergedHistograms() {
+	for _, merged := range o.mergedHistograms {
+		fields := map[string]interface{}{merged.field: merged.dist}
+		oMetric, err := convertTelegrafToOtelMetrics(&o.cfg, merged.measurement, fields, merged.tags, telegraf.Histogram, merged.timestamp)
+		if err != nil {
+			o.logger.Warn("Convert merged histogram failed", zap.String("field", merged.field), zap.Error(err))
+			continue
+		}
+		o.processOtelMetrics(oMetric)
+		o.appendResourceMetrics(oMetric)
+	}
+	o.mergedHistograms = nil
+}
+
+// collectHistogramBuckets removes the bucket (suffixed with HistogramBucketFieldSuffix and tagged with le),
+// count and sum fields of Prometheus style histograms from the metric and collects them into the histograms
+// reconstructed on the next GetOtelMetrics.
+func (o *otelAccumulator) collectHistogramBuckets(m telegraf.Metric) error {
+	suffix := o.cfg.HistogramBucketFieldSuffix
+	if suffix == "" {
+		return nil
+	}
+
+	le, hasLe := m.GetTag(leTag)
+	tags := make(map[string]string, len(m.Tags()))
+	for k, v := range m.Tags() {
+		if k != leTag {
+			tags[k] = v
+		}
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	var errs []string
+	for field, value := range m.Fields() {
+		var base string
+		switch {
+		case hasLe && strings.HasSuffix(field, suffix):
+			base = strings.TrimSuffix(field, suffix)
+		case !hasLe && strings.HasSuffix(field, "_count"):
+			base = strings.TrimSuffix(field, "_count")
+		case !hasLe && strings.HasSuffix(field, "_sum"):
+			base = strings.TrimSuffix(field, "_sum")
+		default:
+			continue
+		}
+		v, ok := toFloat64(value)
+		if !ok {
+			continue
+		}
+
+		key := seriesKey(metricName(&o.cfg, m.Name(), base), tags)
+		h, ok := o.bucketHistograms[key]
+		if !ok {
+			h = &bucketHistogram{measurement: m.Name(), base: base, tags: tags, buckets: map[float64]float64{}}
+			if o.bucketHistograms == nil {
+				o.bucketHistograms = map[string]*bucketHistogram{}
+			}
+			o.bucketHistograms[key] = h
+		}
+		if m.Time().After(h.timestamp) {
+			h.timestamp = m.Time()
+		}
+		m.RemoveField(field)
+
+		switch {
+		case hasLe:
+			bound, err := strconv.ParseFloat(le, 64)
+			if err != nil || math.IsNaN(bound) {
+				errs = append(errs, fmt.Sprintf("field (%q): invalid bucket bound %q", field, le))
+				continue
+			}
+			h.buckets[bound] = v
+		case strings.HasSuffix(field, "_count"):
+			h.count, h.hasCount = v, true
+		default:
+			h.sum = v
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("histogram buckets: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// flushBucketHistograms converts the reconstructed Prometheus style histograms and appends them to the accumulated
+// metrics. The caller must hold the mutex.
+func (o *otelAccumulator) flushBucketHistograms() {
+	for _, h := range o.bucketHistograms {
+		otelMetrics := pmetric.NewMetrics()
+		populate := func(cfg *Config, measurement string, metrics pmetric.MetricSlice, _ map[string]interface{}, tags map[string]string, timestamp pcommon.Timestamp) {
+			m := metrics.AppendEmpty()
+			name := metricName(cfg, measurement, h.base)
+			m.SetName(name)
+			m.SetUnit(getUnit(cfg, measurement, h.base, name))
+			dp := m.SetEmptyHistogram().DataPoints().AppendEmpty()
+			dp.SetTimestamp(timestamp)
+			h.populate(dp)
+			addTagsToAttributes(cfg, dp.Attributes(), tags)
+		}
+		AddScopeMetricsIntoOtelMetrics(&o.cfg, populate, otelMetrics, h.measurement, nil, h.tags, h.timestamp)
+		o.processOtelMetrics(otelMetrics)
+		o.appendResourceMetrics(otelMetrics)
+	}
+	o.bucketHistograms = nil
+}
+
+// populate converts the cumulative buckets into the explicit bounds and per bucket counts of the data point.
+// The +Inf bucket is the implicit overflow bucket, so it is not part of the explicit bounds.
+func (h *bucketHistogram) populate(dp pmetric.HistogramDataPoint) {
+	bounds := make([]float64, 0, len(h.buckets))
+	for bound := range h.buckets {
+		if !math.IsInf(bound, 1) {
+			bounds = append(bounds, bound)
+		}
+	}
+	sort.Float64s(bounds)
+
+	total, hasInf := h.buckets[math.Inf(1)]
+	if h.hasCount {
+		total = h.count
+	} else if !hasInf && len(bounds) > 0 {
+		total = h.buckets[bounds[len(bounds)-1]]
+	}
+
+	var previous float64
+	for _, bound := range bounds {
+		cumulative := h.buckets[bound]
+		dp.ExplicitBounds().Append(bound)
+		dp.BucketCounts().Append(uint64(math.Max(cumulative-previous, 0)))
+		previous = math.Max(cumulative, previous)
+	}
+	dp.BucketCounts().Append(uint64(math.Max(total-previous, 0)))
+	dp.SetCount(uint64(total))
+	dp.SetSum(h.sum)
+}
+
+// BucketMergeStrategy decides which adjacent buckets are merged first when a histogram exceeds MaxExplicitBounds.
+type BucketMergeStrategy string
+
+const (
+	// BucketMergeUniform merges groups of the same number of adjacent buckets across the whole range.
+	BucketMergeUniform BucketMergeStrategy = "Uniform"
+	// BucketMergeTail merges the highest buckets into one, keeping the resolution of the low values.
+	BucketMergeTail BucketMergeStrategy = "Tail"
+	// BucketMergeHead merges the lowest buckets into one, keeping the resolution of the high values.
+	BucketMergeHead BucketMergeStrategy = "Head"
+)
+
+// limitExplicitBounds merges adjacent buckets of the data point, as chosen by the strategy, until it has at most
+// maxBounds explicit bounds. Each merged bucket keeps the upper bound of the buckets it covers, so the total count
+// is preserved.
+func limitExplicitBounds(dp pmetric.HistogramDataPoint, maxBounds int, strategy BucketMergeStrategy) {
+	n := dp.ExplicitBounds().Len()
+	if n <= maxBounds {
+		return
+	}
+
+	type bucket struct {
+		bound float64
+		count uint64
+	}
+	buckets := make([]bucket, n)
+	for i := 0; i < n; i++ {
+		buckets[i] = bucket{bound: dp.ExplicitBounds().At(i), count: dp.BucketCounts().At(i)}
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].bound < buckets[j].bound })
+	// an extra count is the overflow bucket above the last bound
+	var overflow []uint64
+	if dp.BucketCounts().Len() > n {
+		overflow = dp.BucketCounts().AsRaw()[n:]
+	}
+
+	bounds := make([]float64, 0, maxBounds)
+	counts := make([]uint64, 0, maxBounds+len(overflow))
+	merge := func(start, end int) {
+		var count uint64
+		for _, b := range buckets[start:end] {
+			count += b.count
+		}
+		bounds = append(bounds, buckets[end-1].bound)
+		counts = append(counts, count)
+	}
+	switch strategy {
+	case BucketMergeTail:
+		for i := 0; i < maxBounds-1; i++ {
+			merge(i, i+1)
+		}
+		merge(maxBounds-1, n)
+	case BucketMergeHead:
+		merged := n - maxBounds + 1
+		merge(0, merged)
+		for i := merged; i < n; i++ {
+			merge(i, i+1)
+		}
+	default:
+		groupSize := (n + maxBounds - 1) / maxBounds
+		for start := 0; start < n; start += groupSize {
+			merge(start, min(start+groupSize, n))
+		}
+	}
+	counts = append(counts, overflow...)
+	dp.ExplicitBounds().FromRaw(bounds)
+	dp.BucketCounts().FromRaw(counts)
+}
+
+// histogramBounds returns the explicit bounds configured for the histogram metric, if any.
+func histogramBounds(cfg *Config, name string) []float64 {
+	if bounds, ok := cfg.HistogramBoundsByName[name]; ok {
+		return bounds
+	}
+	return cfg.HistogramBounds
+}
+
+// rebucket replaces the buckets of the data point with the counts of the distribution values within the
+// bounds. Bucket i counts the values in (bounds[i-1], bounds[i]] and the last bucket the values above
+// the last bound. The fractional weights are rounded on the running total and the last bucket takes the
+// remainder, so the bucket counts add up to the count of the data point.
+func rebucket(dp pmetric.HistogramDataPoint, d distribution.Distribution, bounds []float64) {
+	sorted := slices.Clone(bounds)
+	sort.Float64s(sorted)
+	sorted = slices.Compact(sorted)
+
+	counts := make([]float64, len(sorted)+1)
+	values, weights := d.ValuesAndCounts()
+	for i, value := range values {
+		counts[sort.SearchFloat64s(sorted, value)] += weights[i]
+	}
+	total := dp.Count()
+	bucketCounts := make([]uint64, len(counts))
+	var cumulative float64
+	var assigned uint64
+	for i, count := range counts[:len(counts)-1] {
+		cumulative += count
+		next := min(uint64(math.Round(cumulative)), total)
+		bucketCounts[i] = next - assigned
+		assigned = next
+	}
+	bucketCounts[len(counts)-1] = total - assigned
+	dp.ExplicitBounds().FromRaw(sorted)
+	dp.BucketCounts().FromRaw(bucketCounts)
+}
+
+// addPercentileAttributes adds the HistogramPercentiles of the distribution as attributes of the data point, named
+// after the percentile (e.g. p50, p99.9). The percentiles of an empty distribution are unknown, so no attribute is
+// added.
+func addPercentileAttributes(cfg *Config, attributes pcommon.Map, d distribution.Distribution) {
+	if d.SampleCount() == 0 {
+		return
+	}
+	for _, percentile := range cfg.HistogramPercentiles {
+		attributes.PutDouble(percentileName(percentile), distributionQuantile(d, percentile/100))
+	}
+}
+
+// appendPercentileGauges adds a gauge for each of the EmitPercentilesAsGauges of the distribution, named after the
+// histogram and the percentile (e.g. name_p50). The percentiles of an empty distribution are unknown, so no gauge
+// is added.
+func appendPercentileGauges(cfg *Config, metrics pmetric.MetricSlice, name, unit string, d distribution.Distribution, tags map[string]string, timestamp pcommon.Timestamp) {
+	if d.SampleCount() == 0 {
+		return
+	}
+	for _, percentile := range cfg.EmitPercentilesAsGauges {
+		m := metrics.AppendEmpty()
+		m.SetName(name + "_" + percentileName(percentile))
+		m.SetUnit(unit)
+		populateNumberDataPoint(cfg, m.SetEmptyGauge().DataPoints().AppendEmpty(), distributionQuantile(d, percentile/100), tags, timestamp)
+	}
+}
+
+// percentileName returns the name of the percentile (e.g. p50, p99.9).
+func percentileName(percentile float64) string {
+	return "p" + strconv.FormatFloat(percentile, 'f', -1, 64)
+}
+
+// distributionQuantile returns the weighted q-quantile (0..1) of the values of the distribution, which is the lowest
+// value whose cumulative weight reaches q of the total weight. It returns NaN for an empty distribution. The
+// distributions implementing their own Quantile (e.g. regular and exponential) are queried directly.
+func distributionQuantile(d distribution.Distribution, q float64) float64 {
+	if qd, ok := d.(interface{ Quantile(q float64) float64 }); ok {
+		return qd.Quantile(q)
+	}
+	values, weights := d.ValuesAndCounts()
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	indices := make([]int, len(values))
+	var total float64
+	for i := range indices {
+		indices[i] = i
+		total += weights[i]
+	}
+	sort.Slice(indices, func(i, j int) bool { return values[indices[i]] < values[indices[j]] })
+
+	target := math.Max(0, math.Min(1, q)) * total
+	var cumulative float64
+	for _, i := range indices {
+		cumulative += weights[i]
+		if cumulative >= target {
+			return values[i]
+		}
+	}
+	return values[indices[len(indices)-1]]
+}
@@ -0,0 +1,517 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/metric"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/regular"
+)
+
+func TestMergeHistogramsAcrossAttribute(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.MergeHistogramsAcrossAttribute = map[string]string{"latency": "host"}
+
+	now := time.Now()
+	for host, values := range map[string][]float64{"a": {1, 2, 3}, "b": {10, 20}} {
+		dist := regular.NewRegularDistribution()
+		for _, v := range values {
+			as.NoError(dist.AddEntry(v, 1))
+		}
+		acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{"host": host, "service": "api"}, now)
+	}
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(1, otelMetrics.ResourceMetrics().Len())
+	metrics := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(1, metrics.Len())
+	as.Equal("latency", metrics.At(0).Name())
+	as.Equal(pmetric.MetricTypeHistogram, metrics.At(0).Type())
+	dp := metrics.At(0).Histogram().DataPoints().At(0)
+	as.Equal(uint64(5), dp.Count())
+	as.Equal(36.0, dp.Sum())
+	as.Equal(1.0, dp.Min())
+	as.Equal(20.0, dp.Max())
+	as.Equal(map[string]any{"service": "api"}, dp.Attributes().AsRaw())
+
+	// the merged histograms are reset after each flush
+	as.Equal(pmetric.NewMetrics(), acc.GetOtelMetrics())
+}
+
+func TestHistogramBucketFieldSuffix(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.HistogramBucketFieldSuffix = "_bucket"
+
+	now := time.Now()
+	tags := map[string]string{"path": "/"}
+	withLe := func(le string) map[string]string {
+		return map[string]string{"path": "/", "le": le}
+	}
+	acc.AddHistogram("prometheus", map[string]interface{}{"http_latency_count": 10.0, "http_latency_sum": 4.2}, tags, now)
+	// buckets arrive out of order and include the +Inf bucket
+	acc.AddHistogram("prometheus", map[string]interface{}{"http_latency_bucket": 5.0}, withLe("0.5"), now)
+	acc.AddHistogram("prometheus", map[string]interface{}{"http_latency_bucket": 10.0}, withLe("+Inf"), now)
+	acc.AddHistogram("prometheus", map[string]interface{}{"http_latency_bucket": 2.0}, withLe("0.1"), now)
+	acc.AddHistogram("prometheus", map[string]interface{}{"http_latency_bucket": 7.0}, withLe("1"), now)
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(1, otelMetrics.ResourceMetrics().Len())
+	metrics := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(1, metrics.Len())
+	as.Equal("http_latency", metrics.At(0).Name())
+	dp := metrics.At(0).Histogram().DataPoints().At(0)
+	as.Equal([]float64{0.1, 0.5, 1}, dp.ExplicitBounds().AsRaw())
+	as.Equal([]uint64{2, 3, 2, 3}, dp.BucketCounts().AsRaw())
+	as.Equal(uint64(10), dp.Count())
+	as.Equal(4.2, dp.Sum())
+	as.Equal(map[string]any{"path": "/"}, dp.Attributes().AsRaw())
+	as.Equal(pcommon.NewTimestampFromTime(now), dp.Timestamp())
+}
+
+func TestHistogramFractionalCount(t *testing.T) {
+	as := assert.New(t)
+	dist := regular.NewRegularDistribution()
+	// sampled at a rate of 0.4
+	as.NoError(dist.AddEntry(1, 2.5))
+	as.NoError(dist.AddEntry(2, 2.5))
+	as.NoError(dist.AddEntry(3, 0.25))
+
+	for _, enabled := range []bool{false, true} {
+		acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+		acc.cfg.HistogramFractionalCount = enabled
+		acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{}, time.Now())
+
+		dp := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+		as.Equal(uint64(5), dp.Count())
+		sampleCount, ok := dp.Attributes().Get(sampleCountAttribute)
+		as.Equal(enabled, ok)
+		if enabled {
+			as.Equal(5.25, sampleCount.Double())
+		}
+	}
+}
+
+func TestMaxExplicitBounds(t *testing.T) {
+	as := assert.New(t)
+	dist := regular.NewRegularDistribution()
+	for i := 1; i <= 100; i++ {
+		as.NoError(dist.AddEntry(float64(i), float64(i%3+1)))
+	}
+
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.MaxExplicitBounds = 8
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{}, time.Now())
+
+	dp := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+	as.LessOrEqual(dp.ExplicitBounds().Len(), 8)
+	bounds := dp.ExplicitBounds().AsRaw()
+	for i := 1; i < len(bounds); i++ {
+		as.Less(bounds[i-1], bounds[i])
+	}
+	as.Equal(100.0, bounds[len(bounds)-1])
+	var total uint64
+	for _, count := range dp.BucketCounts().AsRaw() {
+		total += count
+	}
+	as.Equal(uint64(dist.SampleCount()), total)
+	as.Equal(uint64(dist.SampleCount()), dp.Count())
+}
+
+func TestHistogramBoundsFractionalWeights(t *testing.T) {
+	as := assert.New(t)
+	dist := regular.NewRegularDistribution()
+	for i := 1; i <= 10; i++ {
+		as.NoError(dist.AddEntry(float64(i), 0.7))
+	}
+
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.HistogramBounds = []float64{2, 4, 6, 8}
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{}, time.Now())
+
+	dp := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+	as.Equal(uint64(7), dp.Count())
+	// each bucket holds 1.4, which would be truncated to a total of 5
+	as.Equal([]uint64{1, 2, 1, 2, 1}, dp.BucketCounts().AsRaw())
+}
+
+func TestHistogramBoundsByName(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.HistogramBounds = []float64{10, 100}
+	acc.cfg.HistogramBoundsByName = map[string][]float64{
+		"latency": {0.5, 0.1, 1},
+	}
+
+	newDist := func(values ...float64) *regular.RegularDistribution {
+		dist := regular.NewRegularDistribution().(*regular.RegularDistribution)
+		for _, v := range values {
+			as.NoError(dist.AddEntry(v, 1))
+		}
+		return dist
+	}
+	acc.AddHistogram("latency", map[string]interface{}{"value": newDist(0.05, 0.1, 0.3, 2)}, map[string]string{}, time.Now())
+	acc.AddHistogram("size", map[string]interface{}{"value": newDist(5, 50, 500, 5000)}, map[string]string{}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+	latency := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+	as.Equal([]float64{0.1, 0.5, 1}, latency.ExplicitBounds().AsRaw())
+	as.Equal([]uint64{2, 1, 0, 1}, latency.BucketCounts().AsRaw())
+	size := otelMetrics.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+	as.Equal([]float64{10, 100}, size.ExplicitBounds().AsRaw())
+	as.Equal([]uint64{1, 1, 2}, size.BucketCounts().AsRaw())
+}
+
+func TestHistogramBoundsByNameFractionalWeights(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.HistogramBounds = []float64{10, 100}
+	acc.cfg.HistogramBoundsByName = map[string][]float64{
+		"latency": {0.1, 0.5, 1},
+	}
+
+	dist := regular.NewRegularDistribution()
+	for _, v := range []float64{0.05, 0.1, 0.3, 0.4, 0.7, 2} {
+		as.NoError(dist.AddEntry(v, 1.5))
+	}
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{}, time.Now())
+
+	dp := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+	as.Equal([]float64{0.1, 0.5, 1}, dp.ExplicitBounds().AsRaw())
+	as.Equal(uint64(9), dp.Count())
+	as.Equal([]uint64{3, 3, 2, 1}, dp.BucketCounts().AsRaw())
+}
+
+func TestBucketMergeStrategy(t *testing.T) {
+	testCases := map[BucketMergeStrategy]struct {
+		bounds []float64
+		counts []uint64
+	}{
+		BucketMergeTail: {
+			bounds: []float64{1, 2, 3, 10},
+			counts: []uint64{1, 2, 3, 49, 5},
+		},
+		BucketMergeHead: {
+			bounds: []float64{7, 8, 9, 10},
+			counts: []uint64{28, 8, 9, 10, 5},
+		},
+		BucketMergeUniform: {
+			bounds: []float64{3, 6, 9, 10},
+			counts: []uint64{6, 15, 24, 10, 5},
+		},
+	}
+	for strategy, testCase := range testCases {
+		t.Run(string(strategy), func(t *testing.T) {
+			as := assert.New(t)
+			dp := pmetric.NewHistogramDataPoint()
+			for i := 1; i <= 10; i++ {
+				dp.ExplicitBounds().Append(float64(i))
+				dp.BucketCounts().Append(uint64(i))
+			}
+			dp.BucketCounts().Append(5)
+
+			limitExplicitBounds(dp, 4, strategy)
+			as.Equal(testCase.bounds, dp.ExplicitBounds().AsRaw())
+			as.Equal(testCase.counts, dp.BucketCounts().AsRaw())
+			var total uint64
+			for _, count := range dp.BucketCounts().AsRaw() {
+				total += count
+			}
+			as.Equal(uint64(60), total)
+		})
+	}
+}
+
+// nanSumDistribution is a distribution whose sum is corrupted to NaN.
+type nanSumDistribution struct {
+	distribution.Distribution
+}
+
+func (nanSumDistribution) Sum() float64 { return math.NaN() }
+
+func TestDropNonFiniteHistogramSum(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+
+	valid := regular.NewRegularDistribution()
+	as.NoError(valid.AddEntry(1, 1))
+	invalid := regular.NewRegularDistribution()
+	as.NoError(invalid.AddEntry(2, 1))
+	acc.AddHistogram("latency", map[string]interface{}{"get": valid, "put": nanSumDistribution{invalid}}, map[string]string{}, time.Now())
+	acc.AddHistogram("latency", map[string]interface{}{"put": nanSumDistribution{invalid}}, map[string]string{}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(1, otelMetrics.ResourceMetrics().Len())
+	metrics := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(1, metrics.Len())
+	as.Equal(metric.DecorateMetricName("latency", "get"), metrics.At(0).Name())
+	as.Equal(int64(2), acc.DroppedInvalidHistograms())
+}
+
+// partialDistribution only implements the count and sum of a distribution.
+type partialDistribution struct {
+	count, sum float64
+}
+
+func (d partialDistribution) SampleCount() float64 { return d.count }
+func (d partialDistribution) Sum() float64         { return d.sum }
+
+// rangedPartialDistribution also implements the min and max of a distribution, but not its buckets.
+type rangedPartialDistribution struct {
+	partialDistribution
+	min, max float64
+}
+
+func (d rangedPartialDistribution) Minimum() float64 { return d.min }
+func (d rangedPartialDistribution) Maximum() float64 { return d.max }
+
+func TestPartialDistribution(t *testing.T) {
+	fields := map[string]interface{}{
+		"get":     partialDistribution{count: 3, sum: 12},
+		"put":     rangedPartialDistribution{partialDistribution{count: 2, sum: 5}, 1, 4},
+		"invalid": "not a distribution",
+	}
+	for name, configure := range map[string]func(*Config){
+		"Histogram":            func(*Config) {},
+		"ExponentialHistogram": func(cfg *Config) { cfg.ExponentialHistograms = true },
+	} {
+		t.Run(name, func(t *testing.T) {
+			as := assert.New(t)
+			acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+			configure(&acc.cfg)
+
+			as.NotPanics(func() {
+				acc.AddHistogram("latency", fields, map[string]string{"op": "a"}, time.Now())
+			})
+
+			points := map[string]pmetric.HistogramDataPoint{}
+			forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+				for i := 0; i < ms.Len(); i++ {
+					as.Equal(pmetric.MetricTypeHistogram, ms.At(i).Type())
+					points[ms.At(i).Name()] = ms.At(i).Histogram().DataPoints().At(0)
+				}
+			})
+			as.Len(points, 2)
+			get := points[metric.DecorateMetricName("latency", "get")]
+			as.Equal(uint64(3), get.Count())
+			as.Equal(12.0, get.Sum())
+			as.False(get.HasMin())
+			as.Equal(0, get.BucketCounts().Len())
+			as.Equal(map[string]any{"op": "a"}, get.Attributes().AsRaw())
+			put := points[metric.DecorateMetricName("latency", "put")]
+			as.Equal(uint64(2), put.Count())
+			as.Equal(1.0, put.Min())
+			as.Equal(4.0, put.Max())
+		})
+	}
+
+	t.Run("HistogramAsStats", func(t *testing.T) {
+		as := assert.New(t)
+		acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+		acc.cfg.HistogramAsStats = true
+
+		as.NotPanics(func() {
+			acc.AddHistogram("latency", fields, map[string]string{}, time.Now())
+		})
+
+		var names []string
+		forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+			for i := 0; i < ms.Len(); i++ {
+				names = append(names, ms.At(i).Name())
+			}
+		})
+		get := metric.DecorateMetricName("latency", "get")
+		put := metric.DecorateMetricName("latency", "put")
+		as.ElementsMatch([]string{get + "_sum", get + "_count", put + "_min", put + "_max", put + "_sum", put + "_count"}, names)
+	})
+}
+
+func TestAddHistogramWhileAddingEntries(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.MergeHistogramsAcrossAttribute = map[string]string{metric.DecorateMetricName("latency", "get"): "pod"}
+	dist := regular.NewRegularDistribution()
+	as.NoError(dist.AddEntry(1, 1))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			as.NoError(dist.AddEntry(float64(i%10+1), 1))
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		acc.AddHistogram("latency", map[string]interface{}{"get": dist}, map[string]string{"pod": fmt.Sprint(i)}, time.Now())
+	}
+	wg.Wait()
+	count := dist.SampleCount()
+	as.NoError(dist.AddEntry(1, 1000))
+
+	// the merged histogram holds the snapshots taken when they were added
+	dp := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+	as.GreaterOrEqual(dp.Count(), uint64(10))
+	as.LessOrEqual(dp.Count(), uint64(10*count))
+}
+
+func TestDedupeHistograms(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.DedupeHistograms = true
+
+	newDist := func(values ...float64) distribution.Distribution {
+		dist := regular.NewRegularDistribution()
+		for _, v := range values {
+			as.NoError(dist.AddEntry(v, 1))
+		}
+		return dist
+	}
+	tags := map[string]string{"path": "/"}
+	acc.AddHistogram("latency", map[string]interface{}{"value": newDist(1, 2)}, tags, time.Now())
+	acc.AddHistogram("latency", map[string]interface{}{"value": newDist(1, 2)}, tags, time.Now())
+	acc.AddHistogram("latency", map[string]interface{}{"value": newDist(1, 2)}, map[string]string{"path": "/health"}, time.Now())
+	acc.AddHistogram("latency", map[string]interface{}{"value": newDist(1, 3)}, tags, time.Now())
+	as.Equal(3, acc.GetOtelMetrics().ResourceMetrics().Len())
+
+	// the histograms are only deduplicated within an interval
+	acc.AddHistogram("latency", map[string]interface{}{"value": newDist(1, 2)}, tags, time.Now())
+	as.Equal(1, acc.GetOtelMetrics().ResourceMetrics().Len())
+}
+
+func TestHistogramUseAccumulatorClock(t *testing.T) {
+	as := assert.New(t)
+	now := time.Unix(1700000000, 0)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.HistogramUseAccumulatorClock = true
+	acc.cfg.Clock = func() time.Time { return now }
+
+	dist := regular.NewRegularDistribution()
+	as.NoError(dist.AddEntry(1, 1))
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{}, time.Now())
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{})
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, now.Add(time.Minute))
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(3, otelMetrics.ResourceMetrics().Len())
+	for i := 0; i < 2; i++ {
+		dp := otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+		as.Equal(pcommon.NewTimestampFromTime(now), dp.Timestamp())
+	}
+	// other metrics keep the passed time
+	dp := otelMetrics.ResourceMetrics().At(2).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+	as.Equal(pcommon.NewTimestampFromTime(now.Add(time.Minute)), dp.Timestamp())
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	for _, percentiles := range [][]float64{{50, 90, 99}, nil} {
+		as := assert.New(t)
+		dist := regular.NewRegularDistribution()
+		for i := 1; i <= 100; i++ {
+			as.NoError(dist.AddEntry(float64(i), 1))
+		}
+		acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+		acc.SetHistogramPercentiles(percentiles)
+
+		acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{"host": "a"}, time.Now())
+
+		attributes := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0).Attributes()
+		if len(percentiles) == 0 {
+			as.Equal(map[string]any{"host": "a"}, attributes.AsRaw())
+			continue
+		}
+		as.Equal(4, attributes.Len())
+		for name, want := range map[string]float64{"p50": 50, "p90": 90, "p99": 99} {
+			got, ok := attributes.Get(name)
+			as.True(ok, name)
+			as.InDelta(want, got.Double(), 1, name)
+		}
+	}
+}
+
+func TestHistogramPercentilesOfEmptyDistribution(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.SetHistogramPercentiles([]float64{50, 99})
+
+	acc.AddHistogram("latency", map[string]interface{}{"value": regular.NewRegularDistribution()}, map[string]string{"host": "a"}, time.Now())
+
+	attributes := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"host": "a"}, attributes.AsRaw())
+}
+
+func TestDistributionQuantile(t *testing.T) {
+	as := assert.New(t)
+	dist := regular.NewRegularDistribution()
+	as.True(math.IsNaN(distributionQuantile(dist, 0.5)))
+
+	as.NoError(dist.AddEntry(10, 1))
+	as.NoError(dist.AddEntry(20, 3))
+	as.NoError(dist.AddEntry(5, 1))
+	as.Equal(5.0, distributionQuantile(dist, 0))
+	as.Equal(10.0, distributionQuantile(dist, 0.4))
+	as.Equal(20.0, distributionQuantile(dist, 0.5))
+	as.Equal(20.0, distributionQuantile(dist, 1))
+}
+
+func TestEmitPercentilesAsGauges(t *testing.T) {
+	as := assert.New(t)
+	dist := regular.NewRegularDistribution()
+	for i := 1; i <= 200; i++ {
+		as.NoError(dist.AddEntry(float64(i), 1))
+	}
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EmitPercentilesAsGauges = []float64{50, 99}
+
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{"host": "a"}, time.Now())
+
+	types := map[string]pmetric.MetricType{}
+	gauges := map[string]float64{}
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			types[ms.At(i).Name()] = ms.At(i).Type()
+			if ms.At(i).Type() == pmetric.MetricTypeGauge {
+				dp := ms.At(i).Gauge().DataPoints().At(0)
+				as.Equal(map[string]any{"host": "a"}, dp.Attributes().AsRaw())
+				gauges[ms.At(i).Name()] = dp.DoubleValue()
+			}
+		}
+	})
+	as.Equal(map[string]pmetric.MetricType{
+		"latency":     pmetric.MetricTypeHistogram,
+		"latency_p50": pmetric.MetricTypeGauge,
+		"latency_p99": pmetric.MetricTypeGauge,
+	}, types)
+	as.Equal(distributionQuantile(dist, 0.5), gauges["latency_p50"])
+	as.Equal(distributionQuantile(dist, 0.99), gauges["latency_p99"])
+	as.InDelta(100, gauges["latency_p50"], 1)
+	as.InDelta(198, gauges["latency_p99"], 1)
+}
+
+func TestEmitPercentilesAsGaugesOfEmptyDistribution(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EmitPercentilesAsGauges = []float64{50, 99}
+
+	acc.AddHistogram("latency", map[string]interface{}{"value": regular.NewRegularDistribution()}, map[string]string{"host": "a"}, time.Now())
+
+	types := map[string]pmetric.MetricType{}
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			types[ms.At(i).Name()] = ms.At(i).Type()
+		}
+	})
+	as.Equal(map[string]pmetric.MetricType{"latency": pmetric.MetricTypeHistogram}, types)
+}
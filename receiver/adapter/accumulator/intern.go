@@ -0,0 +1,42 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"sync"
+)
+
+// maxInternedStrings bounds the interner so high cardinality attributes cannot grow it indefinitely.
+const maxInternedStrings = 4096
+
+var attributeInterner = newStringInterner(maxInternedStrings)
+
+// stringInterner returns a canonical instance for equal strings, so repeated attribute values
+// across data points point to the same backing memory.
+type stringInterner struct {
+	mutex   sync.Mutex
+	maxSize int
+	values  map[string]string
+}
+
+func newStringInterner(maxSize int) *stringInterner {
+	return &stringInterner{
+		maxSize: maxSize,
+		values:  make(map[string]string),
+	}
+}
+
+func (si *stringInterner) intern(s string) string {
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+	if interned, ok := si.values[s]; ok {
+		return interned
+	}
+	// Start over rather than tracking usage once full, the common values are interned again quickly.
+	if len(si.values) >= si.maxSize {
+		si.values = make(map[string]string, si.maxSize)
+	}
+	si.values[s] = s
+	return s
+}
@@ -0,0 +1,72 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestStringInterner(t *testing.T) {
+	si := newStringInterner(2)
+	a := si.intern(strings.Clone("us-east-1"))
+	b := si.intern(strings.Clone("us-east-1"))
+	assert.Equal(t, "us-east-1", b)
+	assert.Same(t, unsafe.StringData(a), unsafe.StringData(b))
+	assert.Equal(t, 1, len(si.values))
+	si.intern("b")
+	si.intern("c")
+	assert.LessOrEqual(t, len(si.values), 2)
+}
+
+func TestAddTagsToAttributesInterned(t *testing.T) {
+	tags := map[string]string{"region": "us-east-1", "host": "localhost", "empty": ""}
+	plain := pmetric.NewNumberDataPoint()
+	interned := pmetric.NewNumberDataPoint()
+
+	addTagsToAttributes(&Config{}, plain.Attributes(), tags)
+	addTagsToAttributes(&Config{InternAttributes: true}, interned.Attributes(), tags)
+
+	assert.Equal(t, plain.Attributes().AsRaw(), interned.Attributes().AsRaw())
+}
+
+// BenchmarkAddTagsToAttributes builds a batch of data points whose tag values are freshly allocated copies
+// of a few repeated values (as parsed from an input) and reports the heap retained by the batch.
+func BenchmarkAddTagsToAttributes(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		cfg  *Config
+	}{
+		{name: "Plain", cfg: &Config{}},
+		{name: "Interned", cfg: &Config{InternAttributes: true}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var retained int64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				dps := pmetric.NewNumberDataPointSlice()
+				for j := 0; j < 1000; j++ {
+					tags := map[string]string{
+						"region":        strings.Repeat("us-east-1", 4),
+						"instance_type": strings.Repeat("m5.large", 4),
+					}
+					addTagsToAttributes(bc.cfg, dps.AppendEmpty().Attributes(), tags)
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				retained += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+				runtime.KeepAlive(dps)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
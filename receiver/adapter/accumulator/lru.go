@@ -0,0 +1,64 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"container/list"
+)
+
+// defaultMaxSeries is the number of series whose state is remembered when the limit of the option is not set.
+const defaultMaxSeries = 10000
+
+// seriesLRU holds a value per series key, evicting the least recently seen series once the limit is reached so the
+// state does not grow with the cardinality of the metrics. The zero value is ready to use.
+type seriesLRU[V any] struct {
+	elements map[string]*list.Element
+	order    list.List
+}
+
+type seriesLRUEntry[V any] struct {
+	key   string
+	value V
+}
+
+// get returns the value of the series and marks it as the most recently seen.
+func (c *seriesLRU[V]) get(key string) (V, bool) {
+	elem, ok := c.elements[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*seriesLRUEntry[V]).value, true
+}
+
+// put sets the value of the series and marks it as the most recently seen. The least recently seen series are
+// evicted when a new series would exceed the limit, or defaultMaxSeries when the limit is not positive.
+func (c *seriesLRU[V]) put(key string, value V, limit int) {
+	if elem, ok := c.elements[key]; ok {
+		elem.Value.(*seriesLRUEntry[V]).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+	if limit <= 0 {
+		limit = defaultMaxSeries
+	}
+	for len(c.elements) >= limit {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.elements, oldest.Value.(*seriesLRUEntry[V]).key)
+	}
+	if c.elements == nil {
+		c.elements = map[string]*list.Element{}
+	}
+	c.elements[key] = c.order.PushFront(&seriesLRUEntry[V]{key: key, value: value})
+}
+
+// len returns the number of series remembered.
+func (c *seriesLRU[V]) len() int {
+	return len(c.elements)
+}
@@ -0,0 +1,74 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMetadataTTL is how long the instance metadata is cached when MetadataTTL is unset.
+	defaultMetadataTTL = time.Hour
+	// metadataRetryInterval is how long a failing provider is not queried again, unless MetadataTTL is shorter.
+	metadataRetryInterval = time.Minute
+)
+
+// MetadataProvider provides the host metadata (e.g. ImageId on EC2) which is stamped on the resource
+// of every metric. It may be expensive, so it is only queried once per MetadataTTL.
+type MetadataProvider interface {
+	Metadata(ctx context.Context) (map[string]string, error)
+}
+
+// metadataCache holds the metadata of the MetadataProvider until it expires.
+type metadataCache struct {
+	mutex     sync.Mutex
+	values    map[string]string
+	expiresAt time.Time
+}
+
+// instanceMetadata returns the cached instance metadata. It never queries the provider, so it is safe to call
+// with the accumulator mutex held.
+func (o *otelAccumulator) instanceMetadata() map[string]string {
+	if !o.cfg.IncludeInstanceMetadata || o.cfg.MetadataProvider == nil {
+		return nil
+	}
+	c := &o.metadata
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.values
+}
+
+// refreshInstanceMetadata refreshes the cached instance metadata from the provider once it expires. It must be
+// called without the accumulator mutex held, since the provider may be slow.
+// A failed refresh keeps the last known metadata and is retried after metadataRetryInterval, so a transient error
+// neither strips the metadata for the whole TTL nor queries a failing provider for every metric.
+func (o *otelAccumulator) refreshInstanceMetadata() error {
+	if !o.cfg.IncludeInstanceMetadata || o.cfg.MetadataProvider == nil {
+		return nil
+	}
+
+	c := &o.metadata
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := o.now()
+	if now.Before(c.expiresAt) {
+		return nil
+	}
+
+	ttl := o.cfg.MetadataTTL
+	if ttl <= 0 {
+		ttl = defaultMetadataTTL
+	}
+	values, err := o.cfg.MetadataProvider.Metadata(o.ctx)
+	if err != nil {
+		c.expiresAt = now.Add(min(ttl, metadataRetryInterval))
+		return fmt.Errorf("unable to get instance metadata: %w", err)
+	}
+	c.expiresAt = now.Add(ttl)
+	c.values = values
+	return nil
+}
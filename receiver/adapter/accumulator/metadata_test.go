@@ -0,0 +1,139 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMetadataProvider struct {
+	calls int
+	err   error
+}
+
+func (p *fakeMetadataProvider) Metadata(context.Context) (map[string]string, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return map[string]string{"ImageId": "ami-12345"}, nil
+}
+
+func TestIncludeInstanceMetadata(t *testing.T) {
+	as := assert.New(t)
+	now := time.Now()
+	provider := &fakeMetadataProvider{}
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.IncludeInstanceMetadata = true
+	acc.cfg.MetadataProvider = provider
+	acc.cfg.MetadataTTL = time.Minute
+	acc.cfg.Clock = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, now)
+	}
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(3, otelMetrics.ResourceMetrics().Len())
+	for i := 0; i < otelMetrics.ResourceMetrics().Len(); i++ {
+		as.Equal(map[string]any{"ImageId": "ami-12345"}, otelMetrics.ResourceMetrics().At(i).Resource().Attributes().AsRaw())
+	}
+	as.Equal(1, provider.calls)
+
+	now = now.Add(time.Minute)
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, now)
+	as.Equal(2, provider.calls)
+
+	acc.cfg.IncludeInstanceMetadata = false
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, now)
+	otelMetrics = acc.GetOtelMetrics()
+	as.Equal(0, otelMetrics.ResourceMetrics().At(1).Resource().Attributes().Len())
+	as.Equal(2, provider.calls)
+}
+
+func TestInstanceMetadataRefreshFailure(t *testing.T) {
+	as := assert.New(t)
+	now := time.Now()
+	provider := &fakeMetadataProvider{}
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.IncludeInstanceMetadata = true
+	acc.cfg.MetadataProvider = provider
+	acc.cfg.Clock = func() time.Time { return now }
+
+	as.Nil(acc.instanceMetadata())
+	as.NoError(acc.refreshInstanceMetadata())
+	as.Equal(map[string]string{"ImageId": "ami-12345"}, acc.instanceMetadata())
+
+	// The last known metadata is kept and the failing provider is only retried after the retry interval, so the
+	// error is reported once per retry
+	provider.err = errors.New("imds unavailable")
+	now = now.Add(defaultMetadataTTL)
+	for i := 0; i < 3; i++ {
+		err := acc.refreshInstanceMetadata()
+		as.Equal(i == 0, err != nil)
+		as.Equal(map[string]string{"ImageId": "ami-12345"}, acc.instanceMetadata())
+	}
+	as.Equal(2, provider.calls)
+	now = now.Add(metadataRetryInterval / 2)
+	as.NoError(acc.refreshInstanceMetadata())
+	as.Equal(2, provider.calls)
+
+	provider.err = nil
+	now = now.Add(metadataRetryInterval / 2)
+	as.NoError(acc.refreshInstanceMetadata())
+	as.Equal(map[string]string{"ImageId": "ami-12345"}, acc.instanceMetadata())
+	as.Equal(3, provider.calls)
+	now = now.Add(defaultMetadataTTL - time.Second)
+	as.NoError(acc.refreshInstanceMetadata())
+	as.Equal(3, provider.calls)
+}
+
+type lockCheckingMetadataProvider struct {
+	acc    *otelAccumulator
+	locked bool
+}
+
+func (p *lockCheckingMetadataProvider) Metadata(context.Context) (map[string]string, error) {
+	if p.acc.mutex.TryLock() {
+		p.acc.mutex.Unlock()
+	} else {
+		p.locked = true
+	}
+	return map[string]string{"ImageId": "ami-12345"}, nil
+}
+
+func TestInstanceMetadataQueriedWithoutMutex(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	provider := &lockCheckingMetadataProvider{acc: acc}
+	acc.cfg.IncludeInstanceMetadata = true
+	acc.cfg.MetadataProvider = provider
+	acc.cfg.HeartbeatMetricName = "heartbeat"
+
+	// the heartbeat is built under the mutex on flush
+	otelMetrics := acc.GetOtelMetrics()
+	as.False(provider.locked)
+	as.Equal(1, otelMetrics.ResourceMetrics().Len())
+	as.Equal(map[string]any{"ImageId": "ami-12345"}, otelMetrics.ResourceMetrics().At(0).Resource().Attributes().AsRaw())
+}
+
+func TestInstanceMetadataDoesNotOverwrite(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.IncludeInstanceMetadata = true
+	acc.cfg.MetadataProvider = &fakeMetadataProvider{}
+	acc.cfg.ResourceTags = []string{"ImageId"}
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"ImageId": "ami-custom"}, time.Now())
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+	as.Equal(map[string]any{"ImageId": "ami-custom"}, otelMetrics.ResourceMetrics().At(0).Resource().Attributes().AsRaw())
+	as.Equal(map[string]any{"ImageId": "ami-12345"}, otelMetrics.ResourceMetrics().At(1).Resource().Attributes().AsRaw())
+}
@@ -6,6 +6,11 @@ package accumulator
 import (
 	"fmt"
 	"log"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -17,6 +22,10 @@ import (
 )
 
 func ConvertTelegrafToOtelMetrics(measurement string, fields map[string]interface{}, tags map[string]string, tp telegraf.ValueType, t time.Time) (pmetric.Metrics, error) {
+	return convertTelegrafToOtelMetrics(&Config{}, measurement, fields, tags, tp, t)
+}
+
+func convertTelegrafToOtelMetrics(cfg *Config, measurement string, fields map[string]interface{}, tags map[string]string, tp telegraf.ValueType, t time.Time) (pmetric.Metrics, error) {
 	// Instead of converting as tags as resource attributes, CWAgent will convert it to datapoint's attributes.
 	// It would reduce memory consumption and hostmetricscraper does not add attributes to resource attributes.
 	// https://github.com/open-telemetry/opentelemetry-collector-contrib/blob/99d2204f44d42db5eb7db2f7168a68304c9531c2/receiver/hostmetricsreceiver/internal/scraper/cpuscraper/internal/metadata/generated_metrics_v2.go#L225-L249
@@ -24,11 +33,21 @@ func ConvertTelegrafToOtelMetrics(measurement string, fields map[string]interfac
 	otelMetrics := pmetric.NewMetrics()
 	switch tp {
 	case telegraf.Counter:
-		AddScopeMetricsIntoOtelMetrics(populateDataPointsForSum, otelMetrics, measurement, fields, tags, t)
-	case telegraf.Gauge, telegraf.Untyped:
-		AddScopeMetricsIntoOtelMetrics(populateDataPointsForGauge, otelMetrics, measurement, fields, tags, t)
+		AddScopeMetricsIntoOtelMetrics(cfg, populateDataPointsForSum, otelMetrics, measurement, fields, tags, t)
+	case telegraf.Gauge:
+		AddScopeMetricsIntoOtelMetrics(cfg, populateDataPointsForGauge, otelMetrics, measurement, fields, tags, t)
+	case telegraf.Untyped:
+		AddScopeMetricsIntoOtelMetrics(cfg, populateDataPointsForUntyped, otelMetrics, measurement, fields, tags, t)
 	case telegraf.Histogram:
-		AddScopeMetricsIntoOtelMetrics(populateDataPointsForHistogram, otelMetrics, measurement, fields, tags, t)
+		if cfg.HistogramAsStats {
+			AddScopeMetricsIntoOtelMetrics(cfg, populateDataPointsForHistogramStats, otelMetrics, measurement, fields, tags, t)
+		} else if cfg.ExponentialHistograms {
+			AddScopeMetricsIntoOtelMetrics(cfg, populateDataPointsForExponentialHistogram, otelMetrics, measurement, fields, tags, t)
+		} else {
+			AddScopeMetricsIntoOtelMetrics(cfg, populateDataPointsForHistogram, otelMetrics, measurement, fields, tags, t)
+		}
+	case telegraf.Summary:
+		AddScopeMetricsIntoOtelMetrics(cfg, populateDataPointsForSummary, otelMetrics, measurement, fields, tags, t)
 	default:
 		return pmetric.Metrics{}, fmt.Errorf("unsupported Telegraf Metric type %v", tp)
 	}
@@ -36,7 +55,63 @@ func ConvertTelegrafToOtelMetrics(measurement string, fields map[string]interfac
 	return otelMetrics, nil
 }
 
-type dataPointPopulator func(measurement string, metrics pmetric.MetricSlice, fields map[string]interface{}, tags map[string]string, timestamp pcommon.Timestamp)
+// sampleCountAttribute holds the sum of the sample weights of a histogram with HistogramFractionalCount.
+const sampleCountAttribute = "sample_count"
+
+// metricName builds the OTEL metric name from the Telegraf measurement and field.
+func metricName(cfg *Config, measurement, field string) string {
+	field = renameField(cfg, field)
+	switch {
+	case cfg.NameJoiner != nil && cfg.NameSanitizer != nil:
+		return cfg.NameSanitizer(cfg.NameJoiner(measurement, field))
+	case cfg.NameJoiner != nil:
+		return cfg.NameJoiner(measurement, field)
+	case cfg.NameSanitizer != nil:
+		return cfg.NameSanitizer(metric.DecorateMetricNameWithSeparator(measurement, field, " "))
+	}
+	return metric.DecorateMetricName(measurement, field)
+}
+
+// JoinWithSeparator returns a NameJoiner joining the measurement and the field with the separator (e.g. banana.peel
+// with a dot). The field alone is used for the service inputs, and the measurement alone for the value field.
+func JoinWithSeparator(separator string) func(measurement, field string) string {
+	return func(measurement, field string) string {
+		return metric.DecorateMetricNameWithSeparator(measurement, field, separator)
+	}
+}
+
+// SanitizeUnderscore is a NameSanitizer replacing the spaces of the metric names with underscores
+// (e.g. banana_peel), as on Linux.
+func SanitizeUnderscore(name string) string {
+	return strings.ReplaceAll(name, " ", "_")
+}
+
+// SanitizePreserveSpaces is a NameSanitizer keeping the measurement and the field joined with a space
+// (e.g. banana peel), as on Windows.
+func SanitizePreserveSpaces(name string) string {
+	return name
+}
+
+// FieldRename replaces the matches of the regular expression Pattern in the field names with Replacement, which
+// can reference the capture groups (e.g. $1).
+type FieldRename struct {
+	Pattern     string
+	Replacement string
+}
+
+// renameField applies the FieldRenameRegex renames in order to the field name. Invalid patterns are skipped.
+func renameField(cfg *Config, field string) string {
+	for _, rename := range cfg.FieldRenameRegex {
+		re, err := compilePattern(rename.Pattern)
+		if err != nil {
+			continue
+		}
+		field = re.ReplaceAllString(field, rename.Replacement)
+	}
+	return field
+}
+
+type dataPointPopulator func(cfg *Config, measurement string, metrics pmetric.MetricSlice, fields map[string]interface{}, tags map[string]string, timestamp pcommon.Timestamp)
 
 // AddDataPointsIntoMetrics will use Telegraf's field (which holds  subset metrics from the main metrics)
 // and convert to OTEL's datapoint
@@ -61,36 +136,101 @@ type dataPointPopulator func(measurement string, metrics pmetric.MetricSlice, fi
 //	                   											  -->       }]
 //	                   											  -->    }]
 //	                   											  --> }
-func AddScopeMetricsIntoOtelMetrics(populateDataPoints dataPointPopulator, otelMetrics pmetric.Metrics, measurement string, fields map[string]interface{}, tags map[string]string, t time.Time) {
+func AddScopeMetricsIntoOtelMetrics(cfg *Config, populateDataPoints dataPointPopulator, otelMetrics pmetric.Metrics, measurement string, fields map[string]interface{}, tags map[string]string, t time.Time) {
 	rs := otelMetrics.ResourceMetrics().AppendEmpty()
+	if cfg.OnResourceBuilt != nil {
+		defer cfg.OnResourceBuilt(rs.Resource(), tags)
+	}
+	tags = promoteResourceTags(cfg, rs.Resource(), tags)
 	timestamp := pcommon.NewTimestampFromTime(t)
-	metrics := rs.ScopeMetrics().AppendEmpty().Metrics()
-	populateDataPoints(measurement, metrics, fields, tags, timestamp)
+	sm := rs.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(cfg.ScopeName)
+	sm.Scope().SetVersion(cfg.ScopeVersion)
+	if scope, ok := cfg.MeasurementScope[measurement]; ok {
+		sm.Scope().SetName(scope)
+	}
+	populateDataPoints(cfg, measurement, sm.Metrics(), fields, tags, timestamp)
+}
+
+// promoteResourceTags moves the tags listed in ResourceTags to the resource attributes, after applying their
+// ResourceTagTransform, and returns the remaining tags for the data points.
+func promoteResourceTags(cfg *Config, resource pcommon.Resource, tags map[string]string) map[string]string {
+	if len(cfg.ResourceTags) == 0 {
+		return tags
+	}
+	remaining := make(map[string]string, len(tags))
+	for k, v := range tags {
+		if !slices.Contains(cfg.ResourceTags, k) {
+			remaining[k] = v
+			continue
+		}
+		if transform, ok := cfg.ResourceTagTransform[k]; ok {
+			v = transform(v)
+		}
+		resource.Attributes().PutStr(k, v)
+	}
+	return remaining
 }
 
 // Conversion from Influx Gauge to OTEL Gauge
 // https://github.com/influxdata/influxdb-observability/blob/main/docs/metrics.md#gauge-metric
-func populateDataPointsForGauge(measurement string, metrics pmetric.MetricSlice, fields map[string]interface{}, tags map[string]string, timestamp pcommon.Timestamp) {
+func populateDataPointsForGauge(cfg *Config, measurement string, metrics pmetric.MetricSlice, fields map[string]interface{}, tags map[string]string, timestamp pcommon.Timestamp) {
 	for field, value := range fields {
 		m := metrics.AppendEmpty()
 
-		name := metric.DecorateMetricName(measurement, field)
-		unit := getDefaultUnit(measurement, field)
+		name := metricName(cfg, measurement, field)
+		unit := getUnit(cfg, measurement, field, name)
 		m.SetName(name)
 		m.SetUnit(unit)
 
-		populateNumberDataPoint(m.SetEmptyGauge().DataPoints().AppendEmpty(), value, tags, timestamp)
+		populateNumberDataPoint(cfg, m.SetEmptyGauge().DataPoints().AppendEmpty(), value, tags, timestamp)
+	}
+}
+
+// totalSuffix is the suffix of the untyped fields converted into sums with InferSumFromName.
+const totalSuffix = "_total"
+
+// populateDataPointsForUntyped converts the untyped fields into gauges, except for the fields with the _total suffix
+// which are converted into sums with InferSumFromName. The untyped metrics were not resolved to another type by the
+// type overrides, which take precedence.
+func populateDataPointsForUntyped(cfg *Config, measurement string, metrics pmetric.MetricSlice, fields map[string]interface{}, tags map[string]string, timestamp pcommon.Timestamp) {
+	if !cfg.InferSumFromName {
+		populateDataPointsForGauge(cfg, measurement, metrics, fields, tags, timestamp)
+		return
+	}
+	for field, value := range fields {
+		if strings.HasSuffix(field, totalSuffix) {
+			populateDataPointsForSum(cfg, measurement, metrics, map[string]interface{}{field: value}, tags, timestamp)
+		} else {
+			populateDataPointsForGauge(cfg, measurement, metrics, map[string]interface{}{field: value}, tags, timestamp)
+		}
 	}
 }
 
 // Conversion from Influx Counter to OTEL Sum
 // https://github.com/influxdata/influxdb-observability/blob/main/docs/metrics.md#sum-metric
-func populateDataPointsForSum(measurement string, metrics pmetric.MetricSlice, fields map[string]interface{}, tags map[string]string, timestamp pcommon.Timestamp) {
+func populateDataPointsForSum(cfg *Config, measurement string, metrics pmetric.MetricSlice, fields map[string]interface{}, tags map[string]string, timestamp pcommon.Timestamp) {
 	for field, value := range fields {
+		name := metricName(cfg, measurement, field)
+		if slices.Contains(cfg.ForceGaugeNames, name) {
+			populateDataPointsForGauge(cfg, measurement, metrics, map[string]interface{}{field: value}, tags, timestamp)
+			continue
+		}
+		if isNegative(value) {
+			switch cfg.NegativeCounterPolicy {
+			case NegativeCounterDrop:
+				continue
+			case NegativeCounterAsGauge:
+				populateDataPointsForGauge(cfg, measurement, metrics, map[string]interface{}{field: value}, tags, timestamp)
+				continue
+			case NegativeCounterClamp:
+				value = zeroOf(value)
+			}
+		}
+
 		m := metrics.AppendEmpty()
 
-		name := metric.DecorateMetricName(measurement, field)
-		unit := getDefaultUnit(measurement, field)
+		unit := getUnit(cfg, measurement, field, name)
 		m.SetName(name)
 		m.SetUnit(unit)
 
@@ -100,13 +240,15 @@ func populateDataPointsForSum(measurement string, metrics pmetric.MetricSlice, f
 		// For more information on OTEL Stream Model Sum, please following this document
 		// https://opentelemetry.io/docs/reference/specification/metrics/datamodel/#sums
 		sumMetric := m.SetEmptySum()
-		sumMetric.SetIsMonotonic(true)
-		sumMetric.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
-		populateNumberDataPoint(sumMetric.DataPoints().AppendEmpty(), value, tags, timestamp)
+		behavior := sumBehavior(cfg, measurement, name)
+		sumMetric.SetIsMonotonic(behavior.Monotonic)
+		sumMetric.SetAggregationTemporality(behavior.Temporality)
+		populateNumberDataPoint(cfg, sumMetric.DataPoints().AppendEmpty(), value, tags, timestamp)
 	}
 }
 
 func populateDataPointsForHistogram(
+	cfg *Config,
 	measurement string,
 	metrics pmetric.MetricSlice,
 	fields map[string]interface{},
@@ -116,19 +258,274 @@ func populateDataPointsForHistogram(
 	for field, value := range fields {
 		d, ok := value.(distribution.Distribution)
 		if !ok {
+			populateDegradedHistogram(cfg, measurement, field, metrics, value, tags, timestamp)
 			continue
 		}
 		m := metrics.AppendEmpty()
-		m.SetName(metric.DecorateMetricName(measurement, field))
-		m.SetUnit(getDefaultUnit(measurement, field))
+		name := metricName(cfg, measurement, field)
+		unit := getUnit(cfg, measurement, field, name)
+		m.SetName(name)
+		m.SetUnit(unit)
 		h := m.SetEmptyHistogram().DataPoints().AppendEmpty()
 		h.SetTimestamp(timestamp)
-		d.ConvertToOtel(h)
-		addTagsToAttributes(h.Attributes(), tags)
+		populateExplicitBuckets(cfg, name, h, d)
+		addTagsToAttributes(cfg, h.Attributes(), tags)
+		// The data point count is an integer, so weighted samples lose their fractional weight
+		if cfg.HistogramFractionalCount {
+			h.Attributes().PutDouble(sampleCountAttribute, d.SampleCount())
+		}
+		addPercentileAttributes(cfg, h.Attributes(), d)
+		appendPercentileGauges(cfg, metrics, name, unit, d, tags, timestamp)
+	}
+}
+
+// populateExplicitBuckets sets the explicit buckets of the data point from the distribution, rebucketed into the
+// HistogramBounds of the metric and capped to MaxExplicitBounds when configured.
+func populateExplicitBuckets(cfg *Config, name string, dp pmetric.HistogramDataPoint, d distribution.Distribution) {
+	d.ConvertToOtel(dp)
+	if bounds := histogramBounds(cfg, name); len(bounds) > 0 {
+		rebucket(dp, d, bounds)
+	}
+	if cfg.MaxExplicitBounds > 0 {
+		limitExplicitBounds(dp, cfg.MaxExplicitBounds, cfg.BucketMergeStrategy)
+	}
+}
+
+// populateDataPointsForExponentialHistogram converts each distribution into an OTEL exponential histogram, which
+// keeps the shape of the distribution at a higher resolution than its explicit buckets.
+func populateDataPointsForExponentialHistogram(
+	cfg *Config,
+	measurement string,
+	metrics pmetric.MetricSlice,
+	fields map[string]interface{},
+	tags map[string]string,
+	timestamp pcommon.Timestamp,
+) {
+	for field, value := range fields {
+		d, ok := value.(distribution.Distribution)
+		if !ok {
+			// The buckets of a partial distribution are unknown, which an exponential histogram cannot represent
+			populateDegradedHistogram(cfg, measurement, field, metrics, value, tags, timestamp)
+			continue
+		}
+		m := metrics.AppendEmpty()
+		name := metricName(cfg, measurement, field)
+		unit := getUnit(cfg, measurement, field, name)
+		m.SetName(name)
+		m.SetUnit(unit)
+		h := m.SetEmptyExponentialHistogram()
+		h.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		dp := h.DataPoints().AppendEmpty()
+		dp.SetTimestamp(timestamp)
+		if cfg.ExponentialFromExplicitBuckets {
+			explicit := pmetric.NewHistogramDataPoint()
+			explicit.SetTimestamp(timestamp)
+			populateExplicitBuckets(cfg, name, explicit, d)
+			explicitToExponential(explicit, dp, explicitScale(explicit))
+		} else {
+			distributionToExponential(d, dp)
+		}
+		addTagsToAttributes(cfg, dp.Attributes(), tags)
+		if cfg.HistogramFractionalCount {
+			dp.Attributes().PutDouble(sampleCountAttribute, d.SampleCount())
+		}
+		addPercentileAttributes(cfg, dp.Attributes(), d)
+		appendPercentileGauges(cfg, metrics, name, unit, d, tags, timestamp)
+	}
+}
+
+// populateDataPointsForHistogramStats flattens each distribution into min/max gauges and sum/count sums
+// for destinations that prefer statistic metrics over native histograms.
+func populateDataPointsForHistogramStats(
+	cfg *Config,
+	measurement string,
+	metrics pmetric.MetricSlice,
+	fields map[string]interface{},
+	tags map[string]string,
+	timestamp pcommon.Timestamp,
+) {
+	for field, value := range fields {
+		// The min and max of a partial distribution are only emitted when available
+		d, ok := value.(distributionStats)
+		if !ok {
+			continue
+		}
+		name := metricName(cfg, measurement, field)
+		unit := getUnit(cfg, measurement, field, name)
+
+		if r, ok := value.(distributionRange); ok {
+			for _, stat := range []struct {
+				suffix string
+				value  float64
+			}{{"_min", r.Minimum()}, {"_max", r.Maximum()}} {
+				m := metrics.AppendEmpty()
+				m.SetName(name + stat.suffix)
+				m.SetUnit(unit)
+				populateNumberDataPoint(cfg, m.SetEmptyGauge().DataPoints().AppendEmpty(), stat.value, tags, timestamp)
+			}
+		}
+
+		// A distribution only holds the samples of the current interval, so its sum and count are deltas.
+		sumMetric := metrics.AppendEmpty()
+		sumMetric.SetName(name + "_sum")
+		sumMetric.SetUnit(unit)
+		sum := sumMetric.SetEmptySum()
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		populateNumberDataPoint(cfg, sum.DataPoints().AppendEmpty(), d.Sum(), tags, timestamp)
+
+		countMetric := metrics.AppendEmpty()
+		countMetric.SetName(name + "_count")
+		count := countMetric.SetEmptySum()
+		count.SetIsMonotonic(true)
+		count.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		populateNumberDataPoint(cfg, count.DataPoints().AppendEmpty(), d.SampleCount(), tags, timestamp)
+
+		if d, ok := value.(distribution.Distribution); ok {
+			appendPercentileGauges(cfg, metrics, name, unit, d, tags, timestamp)
+		}
+	}
+}
+
+// summaryFields holds the fields of a Telegraf summary which are converted into the same summary data point.
+type summaryFields struct {
+	count     float64
+	hasCount  bool
+	sum       float64
+	quantiles map[float64]float64
+}
+
+// quantileFieldPattern matches the fields holding a quantile of a summary (e.g. latency_p99 or p50).
+var quantileFieldPattern = regexp.MustCompile(`^(?:(.*)_)?p(\d+)$`)
+
+// summaryField splits the field of a summary into the base name of its summary and what it holds. The _count,
+// _sum and _pNN suffixes hold the count, sum and quantiles of the base. Other fields are a summary of their own.
+func summaryField(field string) (base string, kind string, quantile float64) {
+	for _, suffix := range []string{"count", "sum"} {
+		if field == suffix {
+			return "", suffix, 0
+		}
+		if b, ok := strings.CutSuffix(field, "_"+suffix); ok {
+			return b, suffix, 0
+		}
+	}
+	if match := quantileFieldPattern.FindStringSubmatch(field); match != nil {
+		digits := match[2]
+		// p50 and p99 are percentiles while p999 is the 0.999 quantile
+		var q float64
+		var err error
+		switch {
+		case len(digits) <= 2:
+			q, err = strconv.ParseFloat(digits, 64)
+			q /= 100
+		case digits == "100":
+			q = 1
+		default:
+			q, err = strconv.ParseFloat("0."+digits, 64)
+		}
+		if err == nil {
+			return match[1], "quantile", q
+		}
+	}
+	return field, "value", 0
+}
+
+// Conversion from Influx Summary to OTEL Summary
+// https://github.com/influxdata/influxdb-observability/blob/main/docs/metrics.md#summary-metric
+// The count, sum and quantile fields sharing a base name (e.g. latency_count, latency_sum and latency_p99) are
+// converted into a single summary named after the base. Any other field is a summary of a single sample.
+func populateDataPointsForSummary(cfg *Config, measurement string, metrics pmetric.MetricSlice, fields map[string]interface{}, tags map[string]string, timestamp pcommon.Timestamp) {
+	summaries := map[string]*summaryFields{}
+	for field, value := range fields {
+		v, ok := toFloat64(value)
+		if !ok {
+			continue
+		}
+		base, kind, quantile := summaryField(field)
+		s, ok := summaries[base]
+		if !ok {
+			s = &summaryFields{quantiles: map[float64]float64{}}
+			summaries[base] = s
+		}
+		switch kind {
+		case "count":
+			s.count, s.hasCount = v, true
+		case "sum":
+			s.sum = v
+		case "quantile":
+			s.quantiles[quantile] = v
+		default:
+			s.count, s.hasCount, s.sum = 1, true, v
+		}
+	}
+
+	for base, s := range summaries {
+		field := base
+		if field == "" {
+			field = "value"
+		}
+		m := metrics.AppendEmpty()
+		name := metricName(cfg, measurement, field)
+		m.SetName(name)
+		m.SetUnit(getUnit(cfg, measurement, field, name))
+		dp := m.SetEmptySummary().DataPoints().AppendEmpty()
+		dp.SetTimestamp(timestamp)
+		dp.SetCount(uint64(s.count))
+		dp.SetSum(s.sum)
+		quantiles := make([]float64, 0, len(s.quantiles))
+		for q := range s.quantiles {
+			quantiles = append(quantiles, q)
+		}
+		sort.Float64s(quantiles)
+		for _, q := range quantiles {
+			qv := dp.QuantileValues().AppendEmpty()
+			qv.SetQuantile(q)
+			qv.SetValue(s.quantiles[q])
+		}
+		addTagsToAttributes(cfg, dp.Attributes(), tags)
+	}
+}
+
+// SumBehavior is the monotonicity and aggregation temporality of the sums converted from counters.
+type SumBehavior struct {
+	Monotonic bool
+	// Temporality falls back to CounterTemporality when unspecified
+	Temporality pmetric.AggregationTemporality
+}
+
+// sumBehavior returns the behavior of the sum converted from a counter. The behavior configured for the metric
+// name in SumBehaviorByName takes precedence over the ones configured for the measurement and for all counters.
+func sumBehavior(cfg *Config, measurement string, name string) SumBehavior {
+	if behavior, ok := cfg.SumBehaviorByName[name]; ok {
+		if behavior.Temporality == pmetric.AggregationTemporalityUnspecified {
+			behavior.Temporality = counterTemporality(cfg)
+		}
+		return behavior
+	}
+	return SumBehavior{
+		Monotonic:   counterMonotonic(cfg, measurement),
+		Temporality: counterTemporality(cfg),
+	}
+}
+
+// counterMonotonic returns if the sums converted from the counters of the measurement are monotonic. The override
+// for the measurement takes precedence over NonMonotonicCounters.
+func counterMonotonic(cfg *Config, measurement string) bool {
+	if monotonic, ok := cfg.CounterMonotonicByMeasurement[measurement]; ok {
+		return monotonic
+	}
+	return !cfg.NonMonotonicCounters
+}
+
+// counterTemporality returns the aggregation temporality of the sums converted from counters, which defaults
+// to cumulative.
+func counterTemporality(cfg *Config) pmetric.AggregationTemporality {
+	if cfg.CounterTemporality == pmetric.AggregationTemporalityUnspecified {
+		return pmetric.AggregationTemporalityCumulative
 	}
+	return cfg.CounterTemporality
 }
 
-func populateNumberDataPoint(datapoint pmetric.NumberDataPoint, value interface{}, tags map[string]string, timestamp pcommon.Timestamp) {
+func populateNumberDataPoint(cfg *Config, datapoint pmetric.NumberDataPoint, value interface{}, tags map[string]string, timestamp pcommon.Timestamp) {
 	datapoint.SetTimestamp(timestamp)
 
 	switch v := value.(type) {
@@ -140,5 +537,5 @@ func populateNumberDataPoint(datapoint pmetric.NumberDataPoint, value interface{
 		log.Fatalf("Invalid data type %v for NumberDataPoint ", v)
 	}
 
-	addTagsToAttributes(datapoint.Attributes(), tags)
+	addTagsToAttributes(cfg, datapoint.Attributes(), tags)
 }
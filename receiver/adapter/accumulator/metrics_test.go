@@ -5,6 +5,8 @@ package accumulator
 
 import (
 	"math/rand"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -188,7 +190,8 @@ func Test_ConvertTelegrafToOtelMetrics_WithUnsupportTyped(t *testing.T) {
 			"redis_rx": int64(2),
 		},
 		time.Now().UTC(),
-		telegraf.Summary,
+		// every Telegraf value type is supported, so use an unknown one
+		telegraf.ValueType(-1),
 	)
 
 	convertedOtelMetrics, err := ConvertTelegrafToOtelMetrics(tMetric.Name(), tMetric.Fields(), tMetric.Tags(), tMetric.Type(), tMetric.Time())
@@ -323,14 +326,287 @@ func TestPopulateDataPointsForHistogram(t *testing.T) {
 	values, counts := dist.ValuesAndCounts()
 	otelMetrics := pmetric.NewMetrics().ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
 
-	populateDataPointsForHistogram(metricName, otelMetrics, fields, tags, timestamp)
+	populateDataPointsForHistogram(&Config{}, metricName, otelMetrics, fields, tags, timestamp)
 
 	assert.Equal(t, 1, otelMetrics.Len())
 	// Assume there is a data point.
 	dp := otelMetrics.At(0).Histogram().DataPoints().At(0)
-	assert.Equal(t, len(counts), dp.BucketCounts().Len())
+	// the last count is the empty bucket above the highest value
+	assert.Equal(t, len(counts)+1, dp.BucketCounts().Len())
 	assert.Equal(t, len(values), dp.ExplicitBounds().Len())
+	assert.True(t, slices.IsSorted(dp.ExplicitBounds().AsRaw()))
 	assert.Equal(t, dist.Minimum(), dp.Min())
 	assert.Equal(t, dist.Maximum(), dp.Max())
 	assert.Equal(t, dist.Sum(), dp.Sum())
 }
+
+func TestMetricUnits(t *testing.T) {
+	cfg := &Config{
+		MetricUnits: map[string]string{
+			metric.DecorateMetricName("disk", "used_percent"): "None",
+		},
+	}
+	fields := map[string]interface{}{"used_percent": 42.0, "free": int64(10)}
+
+	convertedOtelMetrics, err := convertTelegrafToOtelMetrics(cfg, "disk", fields, map[string]string{}, telegraf.Gauge, time.Now())
+	assert.NoError(t, err)
+
+	metrics := convertedOtelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	assert.Equal(t, 2, metrics.Len())
+	units := map[string]string{}
+	for i := 0; i < metrics.Len(); i++ {
+		units[metrics.At(i).Name()] = metrics.At(i).Unit()
+	}
+	assert.Equal(t, map[string]string{
+		metric.DecorateMetricName("disk", "used_percent"): "None",
+		metric.DecorateMetricName("disk", "free"):         "Bytes",
+	}, units)
+}
+
+func TestNegativeCounterPolicy(t *testing.T) {
+	fields := map[string]interface{}{"errors": int64(-5), "requests": float64(10)}
+	errorsName := metric.DecorateMetricName("http", "errors")
+	requestsName := metric.DecorateMetricName("http", "requests")
+
+	testCases := map[NegativeCounterPolicy]struct {
+		wantTypes  map[string]pmetric.MetricType
+		wantErrors int64
+	}{
+		"": {
+			wantTypes:  map[string]pmetric.MetricType{errorsName: pmetric.MetricTypeSum, requestsName: pmetric.MetricTypeSum},
+			wantErrors: -5,
+		},
+		NegativeCounterDrop: {
+			wantTypes: map[string]pmetric.MetricType{requestsName: pmetric.MetricTypeSum},
+		},
+		NegativeCounterAsGauge: {
+			wantTypes:  map[string]pmetric.MetricType{errorsName: pmetric.MetricTypeGauge, requestsName: pmetric.MetricTypeSum},
+			wantErrors: -5,
+		},
+		NegativeCounterClamp: {
+			wantTypes:  map[string]pmetric.MetricType{errorsName: pmetric.MetricTypeSum, requestsName: pmetric.MetricTypeSum},
+			wantErrors: 0,
+		},
+	}
+	for policy, testCase := range testCases {
+		t.Run(string(policy), func(t *testing.T) {
+			cfg := &Config{NegativeCounterPolicy: policy}
+			otelMetrics, err := convertTelegrafToOtelMetrics(cfg, "http", fields, map[string]string{}, telegraf.Counter, time.Now())
+			assert.NoError(t, err)
+
+			metrics := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			gotTypes := map[string]pmetric.MetricType{}
+			for i := 0; i < metrics.Len(); i++ {
+				m := metrics.At(i)
+				gotTypes[m.Name()] = m.Type()
+				if m.Name() != errorsName {
+					continue
+				}
+				if m.Type() == pmetric.MetricTypeSum {
+					assert.Equal(t, testCase.wantErrors, m.Sum().DataPoints().At(0).IntValue())
+				} else {
+					assert.Equal(t, testCase.wantErrors, m.Gauge().DataPoints().At(0).IntValue())
+				}
+			}
+			assert.Equal(t, testCase.wantTypes, gotTypes)
+		})
+	}
+}
+
+func TestDefaultUnit(t *testing.T) {
+	cfg := &Config{
+		DefaultUnit: "None",
+		MetricUnits: map[string]string{metric.DecorateMetricName("disk", "custom"): "Seconds"},
+	}
+	fields := map[string]interface{}{"used_percent": 42.0, "custom": 1.0, "unknown": 2.0}
+
+	otelMetrics, err := convertTelegrafToOtelMetrics(cfg, "disk", fields, map[string]string{}, telegraf.Gauge, time.Now())
+	assert.NoError(t, err)
+
+	metrics := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	units := map[string]string{}
+	for i := 0; i < metrics.Len(); i++ {
+		units[metrics.At(i).Name()] = metrics.At(i).Unit()
+	}
+	assert.Equal(t, map[string]string{
+		metric.DecorateMetricName("disk", "used_percent"): "Percent",
+		metric.DecorateMetricName("disk", "custom"):       "Seconds",
+		metric.DecorateMetricName("disk", "unknown"):      "None",
+	}, units)
+}
+
+func TestResourceTagTransform(t *testing.T) {
+	cfg := &Config{
+		ResourceTags: []string{"region", "cluster"},
+		ResourceTagTransform: map[string]func(string) string{
+			"region": func(az string) string { return strings.TrimRight(az, "abcdef") },
+		},
+	}
+	tags := map[string]string{"region": "us-east-1a", "cluster": "prod", "host": "a"}
+
+	otelMetrics, err := convertTelegrafToOtelMetrics(cfg, "cpu", map[string]interface{}{"usage_idle": 1.0}, tags, telegraf.Gauge, time.Now())
+	assert.NoError(t, err)
+
+	rm := otelMetrics.ResourceMetrics().At(0)
+	assert.Equal(t, map[string]any{"region": "us-east-1", "cluster": "prod"}, rm.Resource().Attributes().AsRaw())
+	dp := rm.ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+	assert.Equal(t, map[string]any{"host": "a"}, dp.Attributes().AsRaw())
+	// the tags of the Telegraf metric are left untouched
+	assert.Len(t, tags, 3)
+}
+
+func TestOnResourceBuilt(t *testing.T) {
+	cfg := &Config{
+		ResourceTags: []string{"cluster"},
+		OnResourceBuilt: func(resource pcommon.Resource, tags map[string]string) {
+			if tags["service"] == "api" {
+				resource.Attributes().PutStr("entity", "service/api")
+			}
+		},
+	}
+
+	for service, want := range map[string]map[string]any{
+		"api": {"cluster": "prod", "entity": "service/api"},
+		"db":  {"cluster": "prod"},
+	} {
+		tags := map[string]string{"service": service, "cluster": "prod"}
+		otelMetrics, err := convertTelegrafToOtelMetrics(cfg, "http", map[string]interface{}{"requests": 1.0}, tags, telegraf.Gauge, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, want, otelMetrics.ResourceMetrics().At(0).Resource().Attributes().AsRaw())
+	}
+}
+
+func TestMeasurementScope(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.GroupByResource = true
+	acc.cfg.MeasurementScope = map[string]string{"cpu": "system", "http": "web"}
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, time.Now())
+	acc.AddGauge("http", map[string]interface{}{"requests": 1.0}, map[string]string{}, time.Now())
+	acc.AddGauge("cpu", map[string]interface{}{"usage_user": 1.0}, map[string]string{}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(1, otelMetrics.ResourceMetrics().Len())
+	scopes := map[string][]string{}
+	scopeMetrics := otelMetrics.ResourceMetrics().At(0).ScopeMetrics()
+	as.Equal(2, scopeMetrics.Len())
+	for i := 0; i < scopeMetrics.Len(); i++ {
+		metrics := scopeMetrics.At(i).Metrics()
+		for j := 0; j < metrics.Len(); j++ {
+			scopes[scopeMetrics.At(i).Scope().Name()] = append(scopes[scopeMetrics.At(i).Scope().Name()], metrics.At(j).Name())
+		}
+	}
+	as.Equal(map[string][]string{
+		"system": {metric.DecorateMetricName("cpu", "usage_idle"), metric.DecorateMetricName("cpu", "usage_user")},
+		"web":    {metric.DecorateMetricName("http", "requests")},
+	}, scopes)
+}
+
+func TestSetScope(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.SetScope("cpu", "1.2.3")
+	acc.cfg.MeasurementScope = map[string]string{"http": "web"}
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, time.Now())
+	acc.AddGauge("http", map[string]interface{}{"requests": 1.0}, map[string]string{}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+	scope := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Scope()
+	as.Equal("cpu", scope.Name())
+	as.Equal("1.2.3", scope.Version())
+	scope = otelMetrics.ResourceMetrics().At(1).ScopeMetrics().At(0).Scope()
+	as.Equal("web", scope.Name())
+	as.Equal("1.2.3", scope.Version())
+}
+
+func TestUnitFromFieldSuffix(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		as := assert.New(t)
+		acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+		acc.cfg.UnitFromFieldSuffix = enabled
+
+		fields := map[string]interface{}{"request_duration_seconds": 1.5, "heap_bytes": int64(10), "goroutines": int64(3)}
+		acc.AddGauge("app", fields, map[string]string{}, time.Now())
+		acc.AddCounter("app", map[string]interface{}{"sent_bytes": int64(10)}, map[string]string{}, time.Now())
+		// the default unit of the field takes precedence
+		acc.AddGauge("diskio", map[string]interface{}{"read_time_ms": int64(1), "read_bytes": int64(1)}, map[string]string{}, time.Now())
+
+		otelMetrics := acc.GetOtelMetrics()
+		units := map[string]string{}
+		for i := 0; i < otelMetrics.ResourceMetrics().Len(); i++ {
+			metrics := otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics()
+			for j := 0; j < metrics.Len(); j++ {
+				units[metrics.At(j).Name()] = metrics.At(j).Unit()
+			}
+		}
+		want := map[string]string{
+			metric.DecorateMetricName("app", "request_duration_seconds"): "Seconds",
+			metric.DecorateMetricName("app", "heap_bytes"):               "Bytes",
+			metric.DecorateMetricName("app", "goroutines"):               "",
+			metric.DecorateMetricName("app", "sent_bytes"):               "Bytes",
+			metric.DecorateMetricName("diskio", "read_time_ms"):          "Milliseconds",
+			metric.DecorateMetricName("diskio", "read_bytes"):            "Bytes",
+		}
+		if !enabled {
+			want = map[string]string{
+				metric.DecorateMetricName("app", "request_duration_seconds"): "",
+				metric.DecorateMetricName("app", "heap_bytes"):               "",
+				metric.DecorateMetricName("app", "goroutines"):               "",
+				metric.DecorateMetricName("app", "sent_bytes"):               "",
+				metric.DecorateMetricName("diskio", "read_time_ms"):          "",
+				metric.DecorateMetricName("diskio", "read_bytes"):            "Bytes",
+			}
+		}
+		as.Equal(want, units)
+	}
+}
+
+func TestSumBehaviorByName(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.SumBehaviorByName = map[string]SumBehavior{
+		metric.DecorateMetricName("app", "queue_depth"): {Monotonic: false, Temporality: pmetric.AggregationTemporalityDelta},
+	}
+
+	acc.AddCounter("app", map[string]interface{}{"queue_depth": int64(3), "requests": int64(10)}, map[string]string{}, time.Now())
+
+	sums := map[string]pmetric.Sum{}
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			sums[ms.At(i).Name()] = ms.At(i).Sum()
+		}
+	})
+	as.Len(sums, 2)
+	queueDepth := sums[metric.DecorateMetricName("app", "queue_depth")]
+	as.False(queueDepth.IsMonotonic())
+	as.Equal(pmetric.AggregationTemporalityDelta, queueDepth.AggregationTemporality())
+	requests := sums[metric.DecorateMetricName("app", "requests")]
+	as.True(requests.IsMonotonic())
+	as.Equal(pmetric.AggregationTemporalityCumulative, requests.AggregationTemporality())
+}
+
+func TestFieldRenameRegex(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.FieldRenameRegex = []FieldRename{
+		{Pattern: "^usage_", Replacement: ""},
+		{Pattern: "^(.*)_total$", Replacement: "total_$1"},
+	}
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0, "time_total": 2.0, "nice": 3.0}, map[string]string{}, time.Now())
+
+	var names []string
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			names = append(names, ms.At(i).Name())
+		}
+	})
+	as.ElementsMatch([]string{
+		metric.DecorateMetricName("cpu", "idle"),
+		metric.DecorateMetricName("cpu", "total_time"),
+		metric.DecorateMetricName("cpu", "nice"),
+	}, names)
+}
@@ -0,0 +1,44 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+// maxPooledFields is the capacity beyond which a conversion buffer is not returned to the pool, so a single
+// metric with many fields does not keep a large buffer alive.
+const maxPooledFields = 256
+
+// conversionBuffer holds the intermediate values of modifyMetricAndConvertToOtelValue, which are reused across
+// the metrics to reduce the allocations.
+type conversionBuffer struct {
+	fields           []*telegraf.Field
+	stringAttributes map[string]string
+}
+
+var conversionBufferPool = sync.Pool{
+	New: func() any {
+		return &conversionBuffer{stringAttributes: map[string]string{}}
+	},
+}
+
+// getConversionBuffer returns an empty conversion buffer from the pool.
+func getConversionBuffer() *conversionBuffer {
+	return conversionBufferPool.Get().(*conversionBuffer)
+}
+
+// putConversionBuffer resets the conversion buffer and returns it to the pool, unless it grew too large.
+func putConversionBuffer(buf *conversionBuffer) {
+	if cap(buf.fields) > maxPooledFields {
+		return
+	}
+	// Do not keep the fields of the metric alive
+	clear(buf.fields)
+	buf.fields = buf.fields[:0]
+	clear(buf.stringAttributes)
+	conversionBufferPool.Put(buf)
+}
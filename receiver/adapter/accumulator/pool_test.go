@@ -0,0 +1,68 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModifyMetricConcurrently(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.StringFieldsAsAttributes = true
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				fields := map[string]interface{}{
+					"usage":   int32(i),
+					"idle":    float64(g),
+					"up":      true,
+					"state":   fmt.Sprintf("state%d", g),
+					"invalid": []int{i},
+				}
+				m := metric.New("cpu", map[string]string{"cpu": "cpu0"}, fields, time.Now(), telegraf.Gauge)
+				got, err := acc.modifyMetricAndConvertToOtelValue(m)
+				if !as.NoError(err) {
+					return
+				}
+				as.Equal(map[string]interface{}{"usage": int64(i), "idle": float64(g), "up": int64(1)}, got.Fields())
+				as.Equal(map[string]string{"cpu": "cpu0", "state": fmt.Sprintf("state%d", g)}, got.Tags())
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func BenchmarkModifyMetricAndConvertToOtelValue(b *testing.B) {
+	acc := newOtelAccumulatorWithTestRunningInputs(assert.New(b), nil, false)
+	acc.cfg.StringFieldsAsAttributes = true
+	fields := map[string]interface{}{
+		"usage_idle":   1.0,
+		"usage_user":   int32(2),
+		"usage_system": uint64(3),
+		"online":       true,
+		"state":        "running",
+	}
+	tags := map[string]string{"cpu": "cpu0"}
+	now := time.Now()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m := metric.New("cpu", tags, fields, now, telegraf.Gauge)
+		if _, err := acc.modifyMetricAndConvertToOtelValue(m); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const (
+	// scrapeIDAttribute is the attribute holding the id shared by all the data points of a flush.
+	scrapeIDAttribute = "scrape_id"
+	// indexAttribute is the attribute holding the order in which a field was converted within its metric.
+	indexAttribute = "index"
+)
+
+// processOtelMetrics applies the options from the Config which work on the converted OTEL metrics
+// before they are accumulated or consumed.
+func (o *otelAccumulator) processOtelMetrics(metrics pmetric.Metrics) {
+	o.decorateResources(metrics)
+	o.dropDeniedMetrics(metrics)
+	o.injectMetricAttributes(metrics)
+	o.limitAttributes(metrics)
+	removeEmptyResourceMetrics(metrics)
+}
+
+// limitAttributes drops the attributes of the data points over MaxAttributes (e.g. the CloudWatch dimension limit)
+// once all the attributes were added. The injected default attributes from ProcessAttributes and AttributesByMetric
+// are dropped first, then the tags in reverse key order.
+func (o *otelAccumulator) limitAttributes(metrics pmetric.Metrics) {
+	if o.cfg.MaxAttributes <= 0 {
+		return
+	}
+	forEachMetricSlice(metrics, func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			defaults := o.defaultAttributes(ms.At(i).Name())
+			forEachDataPointAttributes(ms.At(i), func(attributes pcommon.Map) {
+				excess := attributes.Len() - o.cfg.MaxAttributes
+				if excess <= 0 {
+					return
+				}
+				type attribute struct {
+					key      string
+					injected bool
+				}
+				candidates := make([]attribute, 0, attributes.Len())
+				attributes.Range(func(k string, v pcommon.Value) bool {
+					value, ok := defaults[k]
+					candidates = append(candidates, attribute{key: k, injected: ok && v.AsString() == value})
+					return true
+				})
+				sort.Slice(candidates, func(i, j int) bool {
+					if candidates[i].injected != candidates[j].injected {
+						return candidates[i].injected
+					}
+					return candidates[i].key > candidates[j].key
+				})
+				for _, candidate := range candidates[:excess] {
+					attributes.Remove(candidate.key)
+				}
+				o.stats.droppedAttributes.Add(int64(excess))
+			})
+		}
+	})
+}
+
+// defaultAttributes returns the attributes injected into the data points of the metric when not already set.
+func (o *otelAccumulator) defaultAttributes(name string) map[string]string {
+	defaults := make(map[string]string, len(o.cfg.ProcessAttributes))
+	for key, value := range o.cfg.ProcessAttributes {
+		defaults[key] = value
+	}
+	for pattern, attributes := range o.cfg.AttributesByMetric {
+		if g, err := compileGlob(pattern); err == nil && g.Match(name) {
+			for key, value := range attributes {
+				defaults[key] = value
+			}
+		}
+	}
+	return defaults
+}
+
+// injectMetricAttributes adds the attributes configured in AttributesByMetric to the data points of the metrics whose
+// name matches the glob. The existing attributes take precedence.
+func (o *otelAccumulator) injectMetricAttributes(metrics pmetric.Metrics) {
+	if len(o.cfg.AttributesByMetric) == 0 {
+		return
+	}
+	forEachMetricSlice(metrics, func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			m := ms.At(i)
+			for pattern, attributes := range o.cfg.AttributesByMetric {
+				g, err := compileGlob(pattern)
+				if err != nil || !g.Match(m.Name()) {
+					continue
+				}
+				forEachDataPointAttributes(m, func(dpAttributes pcommon.Map) {
+					for key, value := range attributes {
+						if _, ok := dpAttributes.Get(key); !ok {
+							dpAttributes.PutStr(key, value)
+						}
+					}
+				})
+			}
+		}
+	})
+}
+
+// dropDeniedMetrics removes the metrics whose name matches one of the DenyMetricPatterns globs.
+func (o *otelAccumulator) dropDeniedMetrics(metrics pmetric.Metrics) {
+	if len(o.cfg.DenyMetricPatterns) == 0 {
+		return
+	}
+	forEachMetricSlice(metrics, func(ms pmetric.MetricSlice) {
+		ms.RemoveIf(func(m pmetric.Metric) bool {
+			for _, pattern := range o.cfg.DenyMetricPatterns {
+				g, err := compileGlob(pattern)
+				if err != nil {
+					continue
+				}
+				if g.Match(m.Name()) {
+					return true
+				}
+			}
+			return false
+		})
+	})
+}
+
+// forEachMetricSlice calls fn with the metrics of every scope.
+func forEachMetricSlice(metrics pmetric.Metrics, fn func(pmetric.MetricSlice)) {
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			fn(scopeMetrics.At(j).Metrics())
+		}
+	}
+}
+
+// stampScrapeID adds a new random id as the scrape_id attribute of every data point of the flushed metrics, so the
+// metrics from the same collection cycle can be correlated.
+func (o *otelAccumulator) stampScrapeID(metrics pmetric.Metrics) {
+	if !o.cfg.StampScrapeID || metrics.ResourceMetrics().Len() == 0 {
+		return
+	}
+	id := uuid.NewString()
+	forEachMetricSlice(metrics, func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			forEachDataPointAttributes(ms.At(i), func(attributes pcommon.Map) {
+				attributes.PutStr(scrapeIDAttribute, id)
+			})
+		}
+	})
+}
+
+// stampDataPointIndex adds the order in which each field of a converted Telegraf metric was converted as the index
+// attribute of its data points.
+func (o *otelAccumulator) stampDataPointIndex(metrics pmetric.Metrics) {
+	if !o.cfg.StampDataPointIndex {
+		return
+	}
+	var index int64
+	forEachMetricSlice(metrics, func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			forEachDataPointAttributes(ms.At(i), func(attributes pcommon.Map) {
+				attributes.PutInt(indexAttribute, index)
+			})
+			index++
+		}
+	})
+}
+
+// forEachDataPointAttributes calls fn with the attributes of every data point of the metric.
+func forEachDataPointAttributes(m pmetric.Metric, fn func(pcommon.Map)) {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		for i := 0; i < m.Gauge().DataPoints().Len(); i++ {
+			fn(m.Gauge().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeSum:
+		for i := 0; i < m.Sum().DataPoints().Len(); i++ {
+			fn(m.Sum().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeHistogram:
+		for i := 0; i < m.Histogram().DataPoints().Len(); i++ {
+			fn(m.Histogram().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		for i := 0; i < m.ExponentialHistogram().DataPoints().Len(); i++ {
+			fn(m.ExponentialHistogram().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeSummary:
+		for i := 0; i < m.Summary().DataPoints().Len(); i++ {
+			fn(m.Summary().DataPoints().At(i).Attributes())
+		}
+	}
+}
+
+// removeEmptyResourceMetrics removes the scopes and resources left without metrics.
+func removeEmptyResourceMetrics(metrics pmetric.Metrics) {
+	metrics.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/metric"
+)
+
+func TestDenyMetricPatterns(t *testing.T) {
+	as := assert.New(t)
+	sink := new(consumertest.MetricsSink)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, sink, true)
+	acc.cfg.DenyMetricPatterns = []string{"*_debug"}
+
+	acc.AddGauge("foo", map[string]interface{}{"value": 1.0}, map[string]string{}, time.Now())
+	acc.AddGauge("foo_debug", map[string]interface{}{"value": 1.0}, map[string]string{}, time.Now())
+	acc.AddGauge("prometheus", map[string]interface{}{"bar": 1.0, "bar_debug": 2.0}, map[string]string{}, time.Now())
+
+	// fully denied metrics are not consumed
+	as.Len(sink.AllMetrics(), 2)
+	var names []string
+	for _, otelMetrics := range sink.AllMetrics() {
+		metrics := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+		for i := 0; i < metrics.Len(); i++ {
+			names = append(names, metrics.At(i).Name())
+		}
+	}
+	as.Equal([]string{"foo", "bar"}, names)
+}
+
+func TestStampScrapeID(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.StampScrapeID = true
+
+	scrapeIDs := func() map[string]int {
+		ids := map[string]int{}
+		otelMetrics := acc.GetOtelMetrics()
+		forEachMetricSlice(otelMetrics, func(ms pmetric.MetricSlice) {
+			for i := 0; i < ms.Len(); i++ {
+				forEachDataPointAttributes(ms.At(i), func(attributes pcommon.Map) {
+					id, ok := attributes.Get("scrape_id")
+					as.True(ok)
+					ids[id.Str()]++
+				})
+			}
+		})
+		return ids
+	}
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0, "usage_user": 2.0}, map[string]string{}, time.Now())
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(1)}, map[string]string{}, time.Now())
+	first := scrapeIDs()
+	as.Len(first, 1)
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{}, time.Now())
+	second := scrapeIDs()
+	as.Len(second, 1)
+	for id, count := range first {
+		as.Equal(3, count)
+		as.NotContains(second, id)
+	}
+}
+
+func TestStampDataPointIndex(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.StampDataPointIndex = true
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0, "usage_user": 2.0, "usage_system": 3.0}, map[string]string{"cpu": "cpu0"}, time.Now())
+	acc.AddGauge("mem", map[string]interface{}{"used": 1.0}, map[string]string{}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+	metrics := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(3, metrics.Len())
+	for i := 0; i < metrics.Len(); i++ {
+		attributes := metrics.At(i).Gauge().DataPoints().At(0).Attributes()
+		as.Equal(map[string]any{"cpu": "cpu0", "index": int64(i)}, attributes.AsRaw())
+	}
+	// the index restarts for each metric
+	attributes := otelMetrics.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"index": int64(0)}, attributes.AsRaw())
+}
+
+func TestAttributesByMetric(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.AttributesByMetric = map[string]map[string]string{metric.DecorateMetricName("mysql", "*"): {"tier": "db"}}
+
+	acc.AddGauge("mysql", map[string]interface{}{"connections": 1.0, "queries": 2.0}, map[string]string{"host": "a"}, time.Now())
+	acc.AddGauge("nginx", map[string]interface{}{"connections": 1.0}, map[string]string{"host": "a"}, time.Now())
+	acc.AddGauge("mysql", map[string]interface{}{"threads": 1.0}, map[string]string{"tier": "primary"}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(3, otelMetrics.ResourceMetrics().Len())
+	attributes := map[string][]map[string]any{}
+	forEachMetricSlice(otelMetrics, func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			attributes[ms.At(i).Name()] = append(attributes[ms.At(i).Name()], ms.At(i).Gauge().DataPoints().At(0).Attributes().AsRaw())
+		}
+	})
+	as.Equal(map[string][]map[string]any{
+		metric.DecorateMetricName("mysql", "connections"): {{"host": "a", "tier": "db"}},
+		metric.DecorateMetricName("mysql", "queries"):     {{"host": "a", "tier": "db"}},
+		metric.DecorateMetricName("nginx", "connections"): {{"host": "a"}},
+		metric.DecorateMetricName("mysql", "threads"):     {{"tier": "primary"}},
+	}, attributes)
+}
+
+func TestMaxAttributes(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.MaxAttributes = 4
+	acc.cfg.ProcessAttributes = map[string]string{"agent_id": "agent-1"}
+	acc.cfg.AttributesByMetric = map[string]map[string]string{"mysql": {"engine": "innodb", "tier": "db"}}
+
+	tags := map[string]string{"host": "a", "region": "us-east-1", "az": "us-east-1a"}
+	acc.AddGauge("mysql", map[string]interface{}{"value": 1.0}, tags, time.Now())
+	// a tag overriding a default is kept as a tag
+	acc.AddGauge("mysql", map[string]interface{}{"value": 1.0}, map[string]string{"host": "a", "region": "us-east-1", "tier": "cache"}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+	attributes := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(4, attributes.Len())
+	for key := range tags {
+		_, ok := attributes.Get(key)
+		as.True(ok, key)
+	}
+	attributes = otelMetrics.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(4, attributes.Len())
+	tier, ok := attributes.Get("tier")
+	as.True(ok)
+	as.Equal("cache", tier.Str())
+	_, ok = attributes.Get("region")
+	as.True(ok)
+	as.EqualValues(3, acc.DroppedAttributes())
+}
@@ -0,0 +1,65 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"go.uber.org/zap"
+)
+
+// rateSuffix is appended to the counter fields to name the gauges of their per second rate.
+const rateSuffix = "_rate"
+
+// counterSample is the last value of a counter series used to compute its rate.
+type counterSample struct {
+	value     float64
+	timestamp time.Time
+}
+
+// counterRates returns the per second rate of each counter field of the metric since the previous value of the
+// same series, keyed by the rate field name. The rate is skipped on the first value, when the time did not advance,
+// and when the counter was reset (i.e. decreased).
+func (o *otelAccumulator) counterRates(m telegraf.Metric) map[string]interface{} {
+	if !o.cfg.EmitCounterRate || m.Type() != telegraf.Counter {
+		return nil
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	rates := map[string]interface{}{}
+	for field, value := range m.Fields() {
+		v, ok := toFloat64(value)
+		if !ok {
+			continue
+		}
+		key := seriesKey(metricName(&o.cfg, m.Name(), field), m.Tags())
+		last, ok := o.lastCounters.get(key)
+		o.lastCounters.put(key, counterSample{value: v, timestamp: m.Time()}, o.cfg.MaxCounterRateSeries)
+		if !ok || v < last.value {
+			continue
+		}
+		interval := m.Time().Sub(last.timestamp).Seconds()
+		if interval <= 0 {
+			continue
+		}
+		rates[field+rateSuffix] = (v - last.value) / interval
+	}
+	return rates
+}
+
+// addCounterRates emits the rates of the counter fields of the metric as gauges.
+func (o *otelAccumulator) addCounterRates(m telegraf.Metric) {
+	rates := o.counterRates(m)
+	if len(rates) == 0 {
+		return
+	}
+	oMetric, err := convertTelegrafToOtelMetrics(&o.cfg, m.Name(), rates, m.Tags(), telegraf.Gauge, m.Time())
+	if err != nil {
+		o.logger.Warn("Convert counter rates failed", zap.String("name", m.Name()), zap.Error(err))
+		return
+	}
+	o.addOtelMetrics(oMetric)
+}
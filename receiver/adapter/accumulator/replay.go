@@ -0,0 +1,201 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"go.uber.org/zap"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/exponential"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/regular"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/seh1"
+)
+
+// recordedMetric is the serialized form of a Telegraf metric received by the accumulator.
+type recordedMetric struct {
+	Name   string             `json:"name"`
+	Tags   map[string]string  `json:"tags,omitempty"`
+	Fields []recordedField    `json:"fields"`
+	Time   time.Time          `json:"time"`
+	Type   telegraf.ValueType `json:"type"`
+}
+
+// recordedField is a field with its Go type. The scalar values are kept as strings, so the non-finite floats are
+// preserved. The exponential distributions are kept with their buckets, since adding their values again would
+// not restore the same buckets.
+type recordedField struct {
+	Key          string                `json:"key"`
+	Kind         string                `json:"kind"`
+	Value        string                `json:"value,omitempty"`
+	Distribution *recordedDistribution `json:"distribution,omitempty"`
+	Exponential  json.RawMessage       `json:"exponential,omitempty"`
+}
+
+// recordedDistribution holds the weighted values of a distribution, which are added again on replay.
+type recordedDistribution struct {
+	Values  []float64 `json:"values"`
+	Weights []float64 `json:"weights"`
+	Unit    string    `json:"unit,omitempty"`
+}
+
+const (
+	fieldKindInt         = "int"
+	fieldKindUint        = "uint"
+	fieldKindFloat       = "float"
+	fieldKindString      = "string"
+	fieldKindBool        = "bool"
+	fieldKindTime        = "time"
+	fieldKindRegular     = "regular"
+	fieldKindSEH1        = "seh1"
+	fieldKindExponential = "exponential"
+)
+
+// recordInput keeps a serializable copy of the metric received by the accumulator when RecordInputs is enabled.
+// The fields of unsupported types are not recorded.
+func (o *otelAccumulator) recordInput(m telegraf.Metric) {
+	if !o.cfg.RecordInputs {
+		return
+	}
+	recorded := recordedMetric{Name: m.Name(), Tags: m.Tags(), Time: m.Time(), Type: m.Type()}
+	for _, field := range m.FieldList() {
+		f, ok := recordField(field.Key, field.Value)
+		if !ok {
+			o.logger.Debug("Unable to record field", zap.String("name", m.Name()), zap.String("field", field.Key), zap.Any("value", field.Value))
+			continue
+		}
+		recorded.Fields = append(recorded.Fields, f)
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.recordedInputs = append(o.recordedInputs, recorded)
+}
+
+// recordField returns the serializable form of the field.
+func recordField(key string, value interface{}) (recordedField, bool) {
+	f := recordedField{Key: key}
+	switch v := value.(type) {
+	case int64:
+		f.Kind, f.Value = fieldKindInt, strconv.FormatInt(v, 10)
+	case uint64:
+		f.Kind, f.Value = fieldKindUint, strconv.FormatUint(v, 10)
+	case float64:
+		f.Kind, f.Value = fieldKindFloat, strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		f.Kind, f.Value = fieldKindString, v
+	case bool:
+		f.Kind, f.Value = fieldKindBool, strconv.FormatBool(v)
+	case time.Time:
+		f.Kind, f.Value = fieldKindTime, v.Format(time.RFC3339Nano)
+	case *exponential.ExponentialDistribution:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return f, false
+		}
+		f.Kind, f.Exponential = fieldKindExponential, b
+	case distribution.Distribution:
+		switch v.(type) {
+		case *regular.RegularDistribution:
+			f.Kind = fieldKindRegular
+		case *seh1.SEH1Distribution:
+			f.Kind = fieldKindSEH1
+		default:
+			return f, false
+		}
+		values, weights := v.ValuesAndCounts()
+		f.Distribution = &recordedDistribution{Values: values, Weights: weights, Unit: v.Unit()}
+	default:
+		return f, false
+	}
+	return f, true
+}
+
+// value returns the field value with its original Go type.
+func (f recordedField) value() (interface{}, error) {
+	switch f.Kind {
+	case fieldKindInt:
+		return strconv.ParseInt(f.Value, 10, 64)
+	case fieldKindUint:
+		return strconv.ParseUint(f.Value, 10, 64)
+	case fieldKindFloat:
+		return strconv.ParseFloat(f.Value, 64)
+	case fieldKindString:
+		return f.Value, nil
+	case fieldKindBool:
+		return strconv.ParseBool(f.Value)
+	case fieldKindTime:
+		return time.Parse(time.RFC3339Nano, f.Value)
+	case fieldKindExponential:
+		if f.Exponential == nil {
+			return nil, fmt.Errorf("invalid distribution")
+		}
+		d := &exponential.ExponentialDistribution{}
+		if err := json.Unmarshal(f.Exponential, d); err != nil {
+			return nil, err
+		}
+		return d, nil
+	case fieldKindRegular, fieldKindSEH1:
+		if f.Distribution == nil || len(f.Distribution.Values) != len(f.Distribution.Weights) {
+			return nil, fmt.Errorf("invalid distribution")
+		}
+		d := regular.NewRegularDistribution()
+		if f.Kind == fieldKindSEH1 {
+			d = seh1.NewSEH1Distribution()
+		}
+		for i, value := range f.Distribution.Values {
+			if err := d.AddEntryWithUnit(value, f.Distribution.Weights[i], f.Distribution.Unit); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("unsupported kind (%q)", f.Kind)
+}
+
+// DumpInputs serializes the Telegraf metrics received since the last GetOtelMetrics, before any conversion, so
+// they can be replayed with ReplayInputs (e.g. to reproduce a production issue in a test). Nothing is recorded
+// unless RecordInputs is enabled.
+func (o *otelAccumulator) DumpInputs() []byte {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	recorded := o.recordedInputs
+	if recorded == nil {
+		recorded = []recordedMetric{}
+	}
+	// The recorded metrics only hold strings, numbers and times, which are always serializable.
+	b, _ := json.Marshal(recorded)
+	return b
+}
+
+// ReplayInputs adds the Telegraf metrics serialized by DumpInputs to the accumulator, as if they were received
+// again.
+func (o *otelAccumulator) ReplayInputs(b []byte) error {
+	var recorded []recordedMetric
+	if err := json.Unmarshal(b, &recorded); err != nil {
+		return fmt.Errorf("invalid inputs: %w", err)
+	}
+	metrics := make([]telegraf.Metric, 0, len(recorded))
+	for _, r := range recorded {
+		fields := make(map[string]interface{}, len(r.Fields))
+		for _, f := range r.Fields {
+			value, err := f.value()
+			if err != nil {
+				return fmt.Errorf("invalid field (%q) of metric (%q): %w", f.Key, r.Name, err)
+			}
+			fields[f.Key] = value
+		}
+		metrics = append(metrics, metric.New(r.Name, r.Tags, fields, r.Time, r.Type))
+	}
+	for _, m := range metrics {
+		o.convertToOtelMetricsAndAddMetric(m)
+	}
+	return nil
+}
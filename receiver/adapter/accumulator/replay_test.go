@@ -0,0 +1,78 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/exponential"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/regular"
+)
+
+func TestDumpAndReplayInputs(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.RecordInputs = true
+	acc.cfg.TimeFieldsAsEpoch = true
+
+	dist := regular.NewRegularDistribution()
+	as.NoError(dist.AddEntryWithUnit(1, 2, "Milliseconds"))
+	as.NoError(dist.AddEntryWithUnit(5, 1, "Milliseconds"))
+	// the values of the exponential buckets are approximate, so adding them again would change the buckets
+	expDist := exponential.NewExponentialDistributionWithScale(3, 4)
+	for i := 0; i < 10; i++ {
+		as.NoError(expDist.AddEntryWithUnit(math.Pow(1.9, float64(i)), 1.5, "Milliseconds"))
+	}
+	now := time.Now()
+	fields := map[string]interface{}{"usage_idle": 1.5, "count": int64(3), "bytes": uint64(math.MaxUint64), "up": true, "state": "ok", "last_seen": now.Add(-time.Minute)}
+	acc.AddGauge("cpu", fields, map[string]string{"host": "a"}, now)
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(10)}, map[string]string{}, now)
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{}, now)
+	acc.AddHistogram("duration", map[string]interface{}{"value": expDist}, map[string]string{}, now)
+
+	dump := acc.DumpInputs()
+	want := acc.GetOtelMetrics()
+	as.JSONEq("[]", string(acc.DumpInputs()))
+
+	replayed := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	replayed.cfg.TimeFieldsAsEpoch = true
+	as.NoError(replayed.ReplayInputs(dump))
+	got := replayed.GetOtelMetrics()
+
+	as.Equal(4, got.ResourceMetrics().Len())
+	as.Equal(want.DataPointCount(), got.DataPointCount())
+	as.Equal(sortedMetrics(want), sortedMetrics(got))
+	as.Error(replayed.ReplayInputs([]byte(`[{"name":"cpu","fields":[{"key":"idle","kind":"complex"}]}]`)))
+}
+
+func TestRecordField(t *testing.T) {
+	as := assert.New(t)
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	for _, value := range []interface{}{math.NaN(), math.Inf(-1), int64(-1), uint64(math.MaxUint64), "", false, now} {
+		f, ok := recordField("field", value)
+		as.True(ok)
+		got, err := f.value()
+		as.NoError(err)
+		if v, ok := value.(float64); ok && math.IsNaN(v) {
+			as.True(math.IsNaN(got.(float64)))
+			continue
+		}
+		as.Equal(value, got)
+	}
+	_, ok := recordField("field", []int{1})
+	as.False(ok)
+}
+
+// sortedMetrics sorts the metrics of every scope by name, since the fields of a metric are converted in any order.
+func sortedMetrics(metrics pmetric.Metrics) pmetric.Metrics {
+	forEachMetricSlice(metrics, func(ms pmetric.MetricSlice) {
+		ms.Sort(func(a, b pmetric.Metric) bool { return a.Name() < b.Name() })
+	})
+	return metrics
+}
@@ -0,0 +1,146 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// resourceFingerprint identifies a resource by its attributes regardless of their order.
+func resourceFingerprint(resource pcommon.Resource) string {
+	attributes := make(map[string]string, resource.Attributes().Len())
+	resource.Attributes().Range(func(k string, v pcommon.Value) bool {
+		attributes[k] = v.AsString()
+		return true
+	})
+	return seriesKey("", attributes)
+}
+
+// decorateResources adds the resource level attributes from the Config to every resource of the metrics.
+func (o *otelAccumulator) decorateResources(metrics pmetric.Metrics) {
+	metadata := o.instanceMetadata()
+	if len(metadata) == 0 && len(o.cfg.ResourceAttributes) == 0 && o.cfg.ResourceProvider == nil {
+		return
+	}
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		attributes := metrics.ResourceMetrics().At(i).Resource().Attributes()
+		// The metadata, provided and static attributes do not overwrite the existing resource attributes
+		for k, v := range metadata {
+			if _, ok := attributes.Get(k); !ok {
+				attributes.PutStr(k, v)
+			}
+		}
+		if o.cfg.ResourceProvider != nil {
+			o.cfg.ResourceProvider().Range(func(k string, v pcommon.Value) bool {
+				if _, ok := attributes.Get(k); !ok {
+					v.CopyTo(attributes.PutEmpty(k))
+				}
+				return true
+			})
+		}
+		for k, v := range o.cfg.ResourceAttributes {
+			if _, ok := attributes.Get(k); !ok {
+				attributes.PutStr(k, v)
+			}
+		}
+	}
+}
+
+// hoistCommonAttributes moves the attributes shared with the same value by all the data points of each resource with
+// several data points to the resource, when HoistCommonAttributes is enabled.
+func (o *otelAccumulator) hoistCommonAttributes(metrics pmetric.Metrics) {
+	if !o.cfg.HoistCommonAttributes {
+		return
+	}
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		rm := metrics.ResourceMetrics().At(i)
+		common := pcommon.NewMap()
+		points := 0
+		forEachResourceDataPointAttributes(rm, func(attributes pcommon.Map) {
+			points++
+			if points == 1 {
+				attributes.CopyTo(common)
+				return
+			}
+			common.RemoveIf(func(k string, v pcommon.Value) bool {
+				other, ok := attributes.Get(k)
+				return !ok || other.Type() != v.Type() || other.AsString() != v.AsString()
+			})
+		})
+		resource := rm.Resource().Attributes()
+		common.RemoveIf(func(k string, _ pcommon.Value) bool {
+			_, ok := resource.Get(k)
+			return ok
+		})
+		if points < 2 || common.Len() == 0 {
+			continue
+		}
+
+		common.Range(func(k string, v pcommon.Value) bool {
+			v.CopyTo(resource.PutEmpty(k))
+			return true
+		})
+		forEachResourceDataPointAttributes(rm, func(attributes pcommon.Map) {
+			attributes.RemoveIf(func(k string, _ pcommon.Value) bool {
+				_, ok := common.Get(k)
+				return ok
+			})
+		})
+	}
+}
+
+// forEachResourceDataPointAttributes calls fn with the attributes of every data point of the resource.
+func forEachResourceDataPointAttributes(rm pmetric.ResourceMetrics, fn func(pcommon.Map)) {
+	for i := 0; i < rm.ScopeMetrics().Len(); i++ {
+		ms := rm.ScopeMetrics().At(i).Metrics()
+		for j := 0; j < ms.Len(); j++ {
+			forEachDataPointAttributes(ms.At(j), fn)
+		}
+	}
+}
+
+// appendResourceMetrics moves the resource metrics from src into the accumulated metrics. With GroupByResource,
+// resource metrics whose resource was already accumulated since the last flush are merged into the existing
+// ResourceMetrics instead of appending a copy of the same resource. The caller must hold the mutex.
+func (o *otelAccumulator) appendResourceMetrics(src pmetric.Metrics) {
+	if !o.cfg.GroupByResource {
+		src.ResourceMetrics().MoveAndAppendTo(o.metrics.ResourceMetrics())
+		return
+	}
+
+	if o.resourceCache == nil {
+		o.resourceCache = map[string]pmetric.ResourceMetrics{}
+	}
+	for i := 0; i < src.ResourceMetrics().Len(); i++ {
+		rm := src.ResourceMetrics().At(i)
+		fingerprint := resourceFingerprint(rm.Resource())
+		cached, ok := o.resourceCache[fingerprint]
+		if !ok {
+			cached = o.metrics.ResourceMetrics().AppendEmpty()
+			rm.MoveTo(cached)
+			o.resourceCache[fingerprint] = cached
+			continue
+		}
+		mergeScopeMetrics(rm.ScopeMetrics(), cached.ScopeMetrics())
+	}
+}
+
+// mergeScopeMetrics moves the metrics of each scope in src into the scope with the same name and version in dest.
+func mergeScopeMetrics(src, dest pmetric.ScopeMetricsSlice) {
+	for i := 0; i < src.Len(); i++ {
+		sm := src.At(i)
+		merged := false
+		for j := 0; j < dest.Len(); j++ {
+			if scope := dest.At(j).Scope(); scope.Name() == sm.Scope().Name() && scope.Version() == sm.Scope().Version() {
+				sm.Metrics().MoveAndAppendTo(dest.At(j).Metrics())
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			sm.MoveTo(dest.AppendEmpty())
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestGroupByResource(t *testing.T) {
+	as := assert.New(t)
+	now := time.Now()
+	add := func(acc *otelAccumulator) {
+		acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"cpu": "cpu0"}, now)
+		acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 2.0}, map[string]string{"cpu": "cpu1"}, now)
+		acc.AddCounter("net", map[string]interface{}{"bytes_sent": int64(3)}, map[string]string{}, now)
+	}
+
+	ungrouped := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	add(ungrouped)
+	want := ungrouped.GetOtelMetrics()
+	as.Equal(3, want.ResourceMetrics().Len())
+
+	grouped := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	grouped.cfg.GroupByResource = true
+	add(grouped)
+	got := grouped.GetOtelMetrics()
+	as.Equal(1, got.ResourceMetrics().Len())
+	as.Equal(1, got.ResourceMetrics().At(0).ScopeMetrics().Len())
+
+	gotMetrics := got.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(want.MetricCount(), gotMetrics.Len())
+	for i := 0; i < want.ResourceMetrics().Len(); i++ {
+		as.Equal(want.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics().At(0), gotMetrics.At(i))
+	}
+
+	// the cache does not outlive the flush
+	add(grouped)
+	as.Equal(1, grouped.GetOtelMetrics().ResourceMetrics().Len())
+}
+
+func TestResourceAttributes(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.ResourceTags = []string{"cluster"}
+	acc.SetResourceAttributes(map[string]string{"deployment.environment": "prod", "cluster": "default"})
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"host": "a"}, time.Now())
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"host": "b", "cluster": "c1"}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+	rm := otelMetrics.ResourceMetrics().At(0)
+	as.Equal(map[string]any{"deployment.environment": "prod", "cluster": "default"}, rm.Resource().Attributes().AsRaw())
+	as.Equal(map[string]any{"host": "a"}, rm.ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes().AsRaw())
+	rm = otelMetrics.ResourceMetrics().At(1)
+	as.Equal(map[string]any{"deployment.environment": "prod", "cluster": "c1"}, rm.Resource().Attributes().AsRaw())
+	as.Equal(map[string]any{"host": "b"}, rm.ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes().AsRaw())
+}
+
+func TestResourceProvider(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.ResourceTags = []string{"host"}
+	acc.SetResourceAttributes(map[string]string{"cluster": "default", "deployment.environment": "prod"})
+	cluster := ""
+	acc.SetResourceProvider(func() pcommon.Map {
+		attributes := pcommon.NewMap()
+		if cluster != "" {
+			attributes.PutStr("cluster", cluster)
+			attributes.PutStr("host", "provided")
+		}
+		return attributes
+	})
+
+	for _, c := range []string{"", "c1", "c2"} {
+		cluster = c
+		acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"host": "a"}, time.Now())
+		want := map[string]any{"cluster": "default", "deployment.environment": "prod", "host": "a"}
+		if c != "" {
+			want["cluster"] = c
+		}
+		otelMetrics := acc.GetOtelMetrics()
+		as.Equal(1, otelMetrics.ResourceMetrics().Len())
+		as.Equal(want, otelMetrics.ResourceMetrics().At(0).Resource().Attributes().AsRaw())
+	}
+}
+
+func TestHoistCommonAttributes(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.GroupByResource = true
+	acc.cfg.HoistCommonAttributes = true
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"region": "us-east-1", "cpu": "cpu0"}, time.Now())
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 2.0}, map[string]string{"region": "us-east-1", "cpu": "cpu1"}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(1, otelMetrics.ResourceMetrics().Len())
+	rm := otelMetrics.ResourceMetrics().At(0)
+	as.Equal(map[string]any{"region": "us-east-1"}, rm.Resource().Attributes().AsRaw())
+	var attributes []map[string]any
+	forEachResourceDataPointAttributes(rm, func(m pcommon.Map) {
+		attributes = append(attributes, m.AsRaw())
+	})
+	as.Equal([]map[string]any{{"cpu": "cpu0"}, {"cpu": "cpu1"}}, attributes)
+
+	// a single data point keeps its attributes
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"region": "us-east-1"}, time.Now())
+	rm = acc.GetOtelMetrics().ResourceMetrics().At(0)
+	as.Equal(0, rm.Resource().Attributes().Len())
+	as.Equal(map[string]any{"region": "us-east-1"}, rm.ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes().AsRaw())
+}
+
+// BenchmarkGroupByResource accumulates repeated resources and marshals each flush the way an exporter would.
+func BenchmarkGroupByResource(b *testing.B) {
+	for _, groupByResource := range []bool{false, true} {
+		name := "Ungrouped"
+		if groupByResource {
+			name = "Grouped"
+		}
+		b.Run(name, func(b *testing.B) {
+			acc := newOtelAccumulatorWithTestRunningInputs(assert.New(b), nil, false)
+			acc.cfg.GroupByResource = groupByResource
+			fields := map[string]interface{}{"usage_idle": 1.0}
+			tags := map[string]string{"cpu": "cpu0"}
+			now := time.Now()
+			marshaler := &pmetric.ProtoMarshaler{}
+			marshaled := 0
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < 100; j++ {
+					acc.AddGauge("cpu", fields, tags, now)
+				}
+				buf, err := marshaler.MarshalMetrics(acc.GetOtelMetrics())
+				if err != nil {
+					b.Fatal(err)
+				}
+				marshaled += len(buf)
+			}
+			b.ReportMetric(float64(marshaled)/float64(b.N), "marshaled-B/op")
+		})
+	}
+}
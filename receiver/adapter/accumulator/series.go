@@ -0,0 +1,83 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"github.com/influxdata/telegraf"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// dropOutOfOrder removes the fields whose timestamp is older than the last timestamp seen for the same series,
+// since CloudWatch may reject points older than the ones already published.
+func (o *otelAccumulator) dropOutOfOrder(m telegraf.Metric) {
+	if !o.cfg.DropOutOfOrder {
+		return
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for field := range m.Fields() {
+		key := seriesKey(metricName(&o.cfg, m.Name(), field), m.Tags())
+		if last, ok := o.lastTimestamps.get(key); ok && m.Time().Before(last) {
+			m.RemoveField(field)
+			o.stats.droppedOutOfOrder.Add(1)
+			continue
+		}
+		o.lastTimestamps.put(key, m.Time(), o.cfg.MaxOutOfOrderSeries)
+	}
+}
+
+// timestampOnlyKey identifies the series of a metric as received from the input, so metrics without fields
+// can be matched with the fields previously received for the same measurement and tags.
+func timestampOnlyKey(m telegraf.Metric) string {
+	return seriesKey(m.Name(), m.Tags())
+}
+
+// rememberSeries keeps the converted metric of the measurement and tags identified by key.
+func (o *otelAccumulator) rememberSeries(key string, m telegraf.Metric) {
+	if m.Type() == telegraf.Histogram {
+		return
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.knownSeries.put(key, m.Copy(), o.cfg.MaxTimestampOnlySeries)
+}
+
+// addTimestampOnlyMetric handles a metric without fields, which inputs emit to advance the time of their series,
+// by emitting a point without a recorded value at the metric timestamp for every series previously converted for
+// the same measurement and tags.
+func (o *otelAccumulator) addTimestampOnlyMetric(m telegraf.Metric) {
+	o.mutex.Lock()
+	known, ok := o.knownSeries.get(timestampOnlyKey(m))
+	o.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	oMetric, err := o.convertToOtelMetrics(known, m.Time().Round(o.precisionFor(m.Name())))
+	if err != nil {
+		o.logger.Warn("Convert timestamp only metric failed", zap.String("name", m.Name()), zap.Error(err))
+		return
+	}
+	noRecordedValue := pmetric.DefaultDataPointFlags.WithNoRecordedValue(true)
+	forEachMetricSlice(oMetric, func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			var dps pmetric.NumberDataPointSlice
+			switch ms.At(i).Type() {
+			case pmetric.MetricTypeGauge:
+				dps = ms.At(i).Gauge().DataPoints()
+			case pmetric.MetricTypeSum:
+				dps = ms.At(i).Sum().DataPoints()
+			default:
+				continue
+			}
+			for j := 0; j < dps.Len(); j++ {
+				dps.At(j).SetFlags(noRecordedValue)
+				dps.At(j).SetDoubleValue(0)
+			}
+		}
+	})
+	o.addOtelMetrics(oMetric)
+}
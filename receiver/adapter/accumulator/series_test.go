@@ -0,0 +1,284 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/metric"
+)
+
+func TestDropOutOfOrder(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.DropOutOfOrder = true
+
+	now := time.Now()
+	tags := map[string]string{defaultInstanceId: defaultInstanceIdValue}
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, tags, now)
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 2.0}, tags, now.Add(-time.Second))
+	// a different series is tracked independently
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 3.0}, map[string]string{defaultInstanceId: "other"}, now.Add(-time.Second))
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+	dp := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+	as.Equal(1.0, dp.DoubleValue())
+	as.Equal(pcommon.NewTimestampFromTime(now), dp.Timestamp())
+	dp = otelMetrics.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+	as.Equal(3.0, dp.DoubleValue())
+	as.EqualValues(1, acc.DroppedOutOfOrder())
+}
+
+func TestDropOutOfOrderBounded(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.DropOutOfOrder = true
+	acc.cfg.MaxOutOfOrderSeries = 2
+
+	now := time.Now()
+	add := func(name string, offset time.Duration) {
+		acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"cpu": name}, now.Add(offset))
+	}
+	add("cpu0", 0)
+	add("cpu1", 0)
+	// cpu0 is seen again, so cpu1 is the least recently seen series when cpu2 is added
+	add("cpu0", time.Second)
+	add("cpu2", 0)
+	as.Equal(2, acc.lastTimestamps.len())
+
+	// cpu0 is still tracked, cpu1 was evicted so its older point is kept
+	add("cpu0", -time.Second)
+	add("cpu1", -time.Second)
+	as.EqualValues(1, acc.DroppedOutOfOrder())
+	as.Equal(2, acc.lastTimestamps.len())
+}
+
+func TestEmitTimestampOnly(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EmitTimestampOnly = true
+
+	tags := map[string]string{"device": "sda"}
+	first := time.Now()
+	second := first.Add(time.Minute)
+
+	// first interval
+	acc.AddGauge("diskio", map[string]interface{}{"reads": 10, "writes": 4.5}, tags, first)
+	// an unknown series without fields is ignored
+	acc.AddGauge("diskio", map[string]interface{}{}, map[string]string{"device": "sdb"}, first)
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(1, otelMetrics.ResourceMetrics().Len())
+	as.Equal(2, otelMetrics.MetricCount())
+
+	// second interval only advances the time
+	acc.AddGauge("diskio", map[string]interface{}{}, tags, second)
+	otelMetrics = acc.GetOtelMetrics()
+	as.Equal(1, otelMetrics.ResourceMetrics().Len())
+	metrics := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(2, metrics.Len())
+	for i := 0; i < metrics.Len(); i++ {
+		dp := metrics.At(i).Gauge().DataPoints().At(0)
+		as.True(dp.Flags().NoRecordedValue())
+		as.Equal(pcommon.NewTimestampFromTime(second), dp.Timestamp())
+		as.Equal(map[string]any{"device": "sda"}, dp.Attributes().AsRaw())
+	}
+
+	acc.cfg.EmitTimestampOnly = false
+	acc.AddGauge("diskio", map[string]interface{}{}, tags, second)
+	as.Equal(0, acc.GetOtelMetrics().ResourceMetrics().Len())
+}
+
+func TestEmitTimestampOnlyBounded(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EmitTimestampOnly = true
+	acc.cfg.MaxTimestampOnlySeries = 1
+
+	now := time.Now()
+	acc.AddGauge("diskio", map[string]interface{}{"reads": 1.0}, map[string]string{"device": "sda"}, now)
+	acc.AddGauge("diskio", map[string]interface{}{"reads": 1.0}, map[string]string{"device": "sdb"}, now)
+	as.Equal(1, acc.knownSeries.len())
+	acc.GetOtelMetrics()
+
+	// sda was evicted by sdb, so only sdb is kept alive
+	acc.AddGauge("diskio", map[string]interface{}{}, map[string]string{"device": "sda"}, now.Add(time.Minute))
+	as.Equal(0, acc.GetOtelMetrics().MetricCount())
+	acc.AddGauge("diskio", map[string]interface{}{}, map[string]string{"device": "sdb"}, now.Add(time.Minute))
+	as.Equal(1, acc.GetOtelMetrics().MetricCount())
+}
+
+func TestEmitCounterRate(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EmitCounterRate = true
+
+	now := time.Now()
+	tags := map[string]string{"interface": "eth0"}
+	rates := func() []float64 {
+		var values []float64
+		otelMetrics := acc.GetOtelMetrics()
+		for i := 0; i < otelMetrics.ResourceMetrics().Len(); i++ {
+			metrics := otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics()
+			for j := 0; j < metrics.Len(); j++ {
+				if metrics.At(j).Name() == metric.DecorateMetricName("net", "bytes_recv_rate") {
+					dp := metrics.At(j).Gauge().DataPoints().At(0)
+					as.Equal(map[string]any{"interface": "eth0"}, dp.Attributes().AsRaw())
+					values = append(values, dp.DoubleValue())
+				}
+			}
+		}
+		return values
+	}
+
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(1000)}, tags, now)
+	as.Empty(rates())
+
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(4000)}, tags, now.Add(10*time.Second))
+	as.Equal([]float64{300}, rates())
+
+	// the counter was reset
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(500)}, tags, now.Add(20*time.Second))
+	as.Empty(rates())
+
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(1500)}, tags, now.Add(30*time.Second))
+	as.Equal([]float64{100}, rates())
+}
+
+func TestEmitCounterRateBounded(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EmitCounterRate = true
+	acc.cfg.MaxCounterRateSeries = 1
+
+	now := time.Now()
+	add := func(name string, value int64, offset time.Duration) {
+		acc.AddCounter("net", map[string]interface{}{"bytes_recv": value}, map[string]string{"interface": name}, now.Add(offset))
+	}
+	add("eth0", 1000, 0)
+	add("eth1", 1000, 0)
+	as.Equal(1, acc.lastCounters.len())
+	acc.GetOtelMetrics()
+
+	// eth0 was evicted by eth1, so its next value starts again without a rate
+	add("eth0", 2000, 10*time.Second)
+	as.Equal(1, acc.lastCounters.len())
+	as.Equal(1, acc.GetOtelMetrics().MetricCount())
+}
+
+func TestStickyMetricNames(t *testing.T) {
+	as := assert.New(t)
+	now := time.Now()
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.StickyMetricNames = []string{metric.DecorateMetricName("disk", "used_percent")}
+	acc.cfg.Clock = func() time.Time { return now }
+
+	tags := map[string]string{"path": "/"}
+	acc.AddGauge("disk", map[string]interface{}{"used_percent": 91.5, "free": int64(10)}, tags, now.Add(-time.Minute))
+	as.Equal(2, acc.GetOtelMetrics().MetricCount())
+
+	// the input produced nothing during this interval
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(1, otelMetrics.MetricCount())
+	m := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	as.Equal(metric.DecorateMetricName("disk", "used_percent"), m.Name())
+	dp := m.Gauge().DataPoints().At(0)
+	as.Equal(91.5, dp.DoubleValue())
+	as.Equal(pcommon.NewTimestampFromTime(now), dp.Timestamp())
+	as.Equal(map[string]any{"path": "/"}, dp.Attributes().AsRaw())
+
+	acc.AddGauge("disk", map[string]interface{}{"used_percent": 50.0}, tags, now)
+	otelMetrics = acc.GetOtelMetrics()
+	as.Equal(1, otelMetrics.MetricCount())
+	as.Equal(50.0, otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).DoubleValue())
+	as.Equal(50.0, acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).DoubleValue())
+
+	acc.ClearStickyMetrics(metric.DecorateMetricName("disk", "used_percent"))
+	as.Equal(0, acc.GetOtelMetrics().MetricCount())
+}
+
+func TestCounterStartTimestamp(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.CounterStartTimestamp = true
+
+	now := time.Now()
+	tags := map[string]string{"interface": "eth0"}
+	startTime := func() pcommon.Timestamp {
+		dp := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints().At(0)
+		as.True(dp.StartTimestamp() <= dp.Timestamp())
+		return dp.StartTimestamp()
+	}
+
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(1000)}, tags, now)
+	as.Equal(pcommon.NewTimestampFromTime(now), startTime())
+
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(4000)}, tags, now.Add(10*time.Second))
+	as.Equal(pcommon.NewTimestampFromTime(now), startTime())
+
+	// reset
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(500)}, tags, now.Add(20*time.Second))
+	as.Equal(pcommon.NewTimestampFromTime(now.Add(20*time.Second)), startTime())
+
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(900)}, tags, now.Add(30*time.Second))
+	as.Equal(pcommon.NewTimestampFromTime(now.Add(20*time.Second)), startTime())
+}
+
+func TestCounterStartTimestampBounded(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.CounterStartTimestamp = true
+	acc.cfg.MaxCounterStartSeries = 2
+
+	for _, name := range []string{"eth0", "eth1", "eth2", "eth3"} {
+		acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(1000)}, map[string]string{"interface": name}, time.Now())
+	}
+	as.Len(acc.counterStarts, 2)
+}
+
+func TestCounterStartTimestampEvictsLeastRecentlySeen(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.CounterStartTimestamp = true
+	acc.cfg.MaxCounterStartSeries = 2
+
+	now := time.Now()
+	startTimes := func() map[string]pcommon.Timestamp {
+		got := map[string]pcommon.Timestamp{}
+		forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+			for i := 0; i < ms.Len(); i++ {
+				dp := ms.At(i).Sum().DataPoints().At(0)
+				name, _ := dp.Attributes().Get("interface")
+				got[name.Str()] = dp.StartTimestamp()
+			}
+		})
+		return got
+	}
+	add := func(name string, value int64, offset time.Duration) {
+		acc.AddCounter("net", map[string]interface{}{"bytes_recv": value}, map[string]string{"interface": name}, now.Add(offset))
+	}
+
+	add("eth0", 100, 0)
+	add("eth1", 100, time.Second)
+	add("eth0", 200, 2*time.Second)
+	// eth1 is the least recently seen series, so it is evicted instead of the hot eth0
+	add("eth2", 100, 3*time.Second)
+	add("eth0", 300, 4*time.Second)
+	as.Equal(map[string]pcommon.Timestamp{
+		"eth0": pcommon.NewTimestampFromTime(now),
+		"eth1": pcommon.NewTimestampFromTime(now.Add(time.Second)),
+		"eth2": pcommon.NewTimestampFromTime(now.Add(3 * time.Second)),
+	}, startTimes())
+	as.Len(acc.counterStarts, 2)
+	as.Contains(acc.counterStarts, seriesKey(metric.DecorateMetricName("net", "bytes_recv"), map[string]string{"interface": "eth0"}))
+
+	add("eth1", 200, 5*time.Second)
+	as.Equal(pcommon.NewTimestampFromTime(now.Add(5*time.Second)), startTimes()["eth1"])
+	as.Equal(2, acc.counterStartOrder.Len())
+}
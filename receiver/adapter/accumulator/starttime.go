@@ -0,0 +1,92 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"container/list"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// defaultMaxCounterStartSeries is the number of counter series whose start time is remembered when
+// MaxCounterStartSeries is not set.
+const defaultMaxCounterStartSeries = 10000
+
+// counterStart is the start time of a cumulative counter series and its last value to detect the resets.
+type counterStart struct {
+	key   string
+	start pcommon.Timestamp
+	value float64
+}
+
+// stampCounterStartTimes sets the start timestamp of the data points of the cumulative sums to the first time their
+// series (i.e. metric name and attributes) was seen. The start timestamp moves to the time of the data point when the
+// value decreases, since the counter was reset (e.g. the process restarted).
+func (o *otelAccumulator) stampCounterStartTimes(metrics pmetric.Metrics) {
+	if !o.cfg.CounterStartTimestamp {
+		return
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	forEachMetricSlice(metrics, func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			m := ms.At(i)
+			if m.Type() != pmetric.MetricTypeSum || m.Sum().AggregationTemporality() != pmetric.AggregationTemporalityCumulative {
+				continue
+			}
+			for j := 0; j < m.Sum().DataPoints().Len(); j++ {
+				dp := m.Sum().DataPoints().At(j)
+				dp.SetStartTimestamp(o.counterStartTime(m.Name(), dp))
+			}
+		}
+	})
+}
+
+// counterStartTime returns the start time of the series of the data point and remembers its value. The caller
+// must hold the mutex.
+func (o *otelAccumulator) counterStartTime(name string, dp pmetric.NumberDataPoint) pcommon.Timestamp {
+	value := dp.DoubleValue()
+	if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		value = float64(dp.IntValue())
+	}
+	key := seriesKey(name, attributesAsStrings(dp.Attributes()))
+
+	if o.counterStarts == nil {
+		o.counterStarts = map[string]*list.Element{}
+	}
+	var last *counterStart
+	if elem, ok := o.counterStarts[key]; ok {
+		last = elem.Value.(*counterStart)
+		o.counterStartOrder.MoveToFront(elem)
+		if value < last.value {
+			last.start = dp.Timestamp()
+		}
+	} else {
+		o.evictCounterStart()
+		last = &counterStart{key: key, start: dp.Timestamp()}
+		o.counterStarts[key] = o.counterStartOrder.PushFront(last)
+	}
+	last.value = value
+	return last.start
+}
+
+// evictCounterStart removes the least recently seen series when the number of remembered series reached the limit,
+// so the state does not grow with the cardinality of the counters. The evicted series starts again when seen next,
+// which only happens to the series not seen for a while.
+func (o *otelAccumulator) evictCounterStart() {
+	limit := o.cfg.MaxCounterStartSeries
+	if limit <= 0 {
+		limit = defaultMaxCounterStartSeries
+	}
+	for len(o.counterStarts) >= limit {
+		oldest := o.counterStartOrder.Back()
+		if oldest == nil {
+			return
+		}
+		o.counterStartOrder.Remove(oldest)
+		delete(o.counterStarts, oldest.Value.(*counterStart).key)
+	}
+}
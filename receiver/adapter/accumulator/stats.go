@@ -0,0 +1,23 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"sync/atomic"
+)
+
+// conversionStats counts the values modified or dropped by the accumulator options. They are only used
+// for troubleshooting, so they are not exported as metrics.
+type conversionStats struct {
+	clampedValues     atomic.Int64
+	droppedOutOfOrder atomic.Int64
+	// droppedInvalidHistograms counts the distributions dropped because their sum is not finite
+	droppedInvalidHistograms atomic.Int64
+	// attributeKeyCollisions counts the tags merged because their keys only differ by case
+	attributeKeyCollisions atomic.Int64
+	// droppedAttributes counts the attributes dropped from the data points over MaxAttributes
+	droppedAttributes atomic.Int64
+	// suppressedErrors counts the identical errors not logged because of the error rate limit
+	suppressedErrors atomic.Int64
+}
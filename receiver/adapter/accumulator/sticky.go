@@ -0,0 +1,83 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"slices"
+
+	"github.com/influxdata/telegraf"
+	"go.uber.org/zap"
+)
+
+// stickySeries holds the last known value of a series listed in StickyMetricNames.
+type stickySeries struct {
+	name        string
+	measurement string
+	field       string
+	tags        map[string]string
+	value       interface{}
+	valueType   telegraf.ValueType
+	// seen is set when the series was added since the last flush
+	seen bool
+}
+
+// rememberStickySeries keeps the last value of the fields of the converted metric whose name is listed in
+// StickyMetricNames.
+func (o *otelAccumulator) rememberStickySeries(m telegraf.Metric) {
+	if len(o.cfg.StickyMetricNames) == 0 || m.Type() == telegraf.Histogram {
+		return
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for field, value := range m.Fields() {
+		name := metricName(&o.cfg, m.Name(), field)
+		if !slices.Contains(o.cfg.StickyMetricNames, name) {
+			continue
+		}
+		if o.stickySeries == nil {
+			o.stickySeries = map[string]*stickySeries{}
+		}
+		o.stickySeries[seriesKey(name, m.Tags())] = &stickySeries{
+			name:        name,
+			measurement: m.Name(),
+			field:       field,
+			tags:        m.Tags(),
+			value:       value,
+			valueType:   m.Type(),
+			seen:        true,
+		}
+	}
+}
+
+// flushStickySeries appends the last known value at the current time of the sticky series which were not added
+// since the last flush. The caller must hold the mutex.
+func (o *otelAccumulator) flushStickySeries() {
+	for _, s := range o.stickySeries {
+		if s.seen {
+			s.seen = false
+			continue
+		}
+		fields := map[string]interface{}{s.field: s.value}
+		oMetric, err := convertTelegrafToOtelMetrics(&o.cfg, s.measurement, fields, s.tags, s.valueType, o.now().Round(o.precisionFor(s.measurement)))
+		if err != nil {
+			o.logger.Warn("Convert sticky series failed", zap.String("name", s.name), zap.Error(err))
+			continue
+		}
+		o.processOtelMetrics(oMetric)
+		o.appendResourceMetrics(oMetric)
+	}
+}
+
+// ClearStickyMetrics stops emitting the last known value of the sticky series of the metric names, or of every
+// sticky series when no name is given.
+func (o *otelAccumulator) ClearStickyMetrics(names ...string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for key, s := range o.stickySeries {
+		if len(names) == 0 || slices.Contains(names, s.name) {
+			delete(o.stickySeries, key)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"maps"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// reassembledSummary holds the quantiles of a summary series added as separate metrics until the next flush.
+type reassembledSummary struct {
+	measurement string
+	field       string
+	tags        map[string]string
+	quantiles   map[float64]float64
+	timestamp   time.Time
+}
+
+// reassembleSummaries removes the fields of the metric tagged with the ReassembleSummaryByQuantileTag and buffers
+// them as a quantile of the summary series identified by the other tags. e.g. latency{quantile=0.5} and
+// latency{quantile=0.99} are emitted as a single latency summary with both quantiles on the next GetOtelMetrics.
+// The metrics with an invalid quantile are left as is.
+func (o *otelAccumulator) reassembleSummaries(m telegraf.Metric) {
+	tag := o.cfg.ReassembleSummaryByQuantileTag
+	if tag == "" {
+		return
+	}
+	value, ok := m.GetTag(tag)
+	if !ok {
+		return
+	}
+	quantile, err := strconv.ParseFloat(value, 64)
+	if err != nil || quantile < 0 || quantile > 1 {
+		o.logger.Debug("Ignored invalid quantile", zap.String("name", m.Name()), zap.String("quantile", value))
+		return
+	}
+
+	tags := m.Tags()
+	delete(tags, tag)
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for field, v := range m.Fields() {
+		fieldValue, ok := toFloat64(v)
+		if !ok {
+			continue
+		}
+
+		key := seriesKey(metricName(&o.cfg, m.Name(), field), tags)
+		s, ok := o.reassembledSummaries[key]
+		if !ok {
+			s = &reassembledSummary{
+				measurement: m.Name(),
+				field:       field,
+				tags:        maps.Clone(tags),
+				quantiles:   map[float64]float64{},
+			}
+			if o.reassembledSummaries == nil {
+				o.reassembledSummaries = map[string]*reassembledSummary{}
+			}
+			o.reassembledSummaries[key] = s
+		}
+		s.quantiles[quantile] = fieldValue
+		if m.Time().After(s.timestamp) {
+			s.timestamp = m.Time()
+		}
+		m.RemoveField(field)
+	}
+}
+
+// flushReassembledSummaries converts the reassembled summaries and appends them to the accumulated metrics.
+// The caller must hold the mutex.
+func (o *otelAccumulator) flushReassembledSummaries() {
+	for _, s := range o.reassembledSummaries {
+		fields := map[string]interface{}{s.field: 0.0}
+		oMetric, err := convertTelegrafToOtelMetrics(&o.cfg, s.measurement, fields, s.tags, telegraf.Summary, s.timestamp)
+		if err != nil {
+			o.logger.Warn("Convert reassembled summary failed", zap.String("field", s.field), zap.Error(err))
+			continue
+		}
+		forEachMetricSlice(oMetric, func(ms pmetric.MetricSlice) {
+			for i := 0; i < ms.Len(); i++ {
+				if ms.At(i).Type() == pmetric.MetricTypeSummary {
+					s.populate(ms.At(i).Summary().DataPoints())
+				}
+			}
+		})
+		o.processOtelMetrics(oMetric)
+		o.appendResourceMetrics(oMetric)
+	}
+	o.reassembledSummaries = nil
+}
+
+// populate replaces the single sample of the converted data points with the quantiles of the summary, in
+// increasing order. The count and sum of the split metrics are unknown.
+func (s *reassembledSummary) populate(dps pmetric.SummaryDataPointSlice) {
+	quantiles := make([]float64, 0, len(s.quantiles))
+	for q := range s.quantiles {
+		quantiles = append(quantiles, q)
+	}
+	sort.Float64s(quantiles)
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		dp.SetCount(0)
+		dp.SetSum(0)
+		dp.QuantileValues().EnsureCapacity(len(quantiles))
+		for _, q := range quantiles {
+			qv := dp.QuantileValues().AppendEmpty()
+			qv.SetQuantile(q)
+			qv.SetValue(s.quantiles[q])
+		}
+	}
+}
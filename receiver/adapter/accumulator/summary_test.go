@@ -0,0 +1,57 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/metric"
+)
+
+func TestReassembleSummaryByQuantileTag(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.ReassembleSummaryByQuantileTag = "quantile"
+
+	now := time.Now()
+	for i, quantile := range []string{"0.99", "0.5", "0.9"} {
+		tags := map[string]string{"service": "api", "quantile": quantile}
+		acc.AddSummary("http", map[string]interface{}{"latency": float64(10 * (i + 1))}, tags, now.Add(time.Duration(i)*time.Second))
+	}
+	acc.AddSummary("http", map[string]interface{}{"latency": 5.0}, map[string]string{"service": "api", "quantile": "high"}, now)
+
+	var summaries []pmetric.Metric
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			summaries = append(summaries, ms.At(i))
+		}
+	})
+	// The metric with an invalid quantile is converted as is
+	if !as.Len(summaries, 2) {
+		return
+	}
+	as.Equal(map[string]any{"service": "api", "quantile": "high"}, summaries[0].Summary().DataPoints().At(0).Attributes().AsRaw())
+
+	m := summaries[1]
+	as.Equal(metric.DecorateMetricName("http", "latency"), m.Name())
+	as.Equal(pmetric.MetricTypeSummary, m.Type())
+	as.Equal(1, m.Summary().DataPoints().Len())
+	dp := m.Summary().DataPoints().At(0)
+	as.Equal(map[string]any{"service": "api"}, dp.Attributes().AsRaw())
+	as.Equal(pcommon.NewTimestampFromTime(now.Add(2*time.Second)), dp.Timestamp())
+	as.Equal(uint64(0), dp.Count())
+	quantiles := map[float64]float64{}
+	for i := 0; i < dp.QuantileValues().Len(); i++ {
+		quantiles[dp.QuantileValues().At(i).Quantile()] = dp.QuantileValues().At(i).Value()
+	}
+	as.Equal(map[float64]float64{0.5: 20, 0.9: 30, 0.99: 10}, quantiles)
+	as.Equal(0.5, dp.QuantileValues().At(0).Quantile())
+
+	as.Equal(0, acc.GetOtelMetrics().ResourceMetrics().Len())
+}
@@ -0,0 +1,211 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"go.uber.org/zap"
+)
+
+// modifyTags applies the tag related options from the Config on the metric before it is converted. None of the
+// steps can fail, so the values listed in HashAttributeValues are always hashed.
+func (o *otelAccumulator) modifyTags(m telegraf.Metric) {
+	if o.cfg.TrimAttributeValues {
+		trimTagValues(m)
+	}
+	o.lowercaseAttributeKeys(m)
+	o.extractAttributes(m)
+	o.pruneConstantAttributes(m)
+	o.renameAttributes(m)
+	o.renameHostDimension(m)
+	o.mapAttributeValues(m)
+	o.hashAttributeValues(m)
+	o.truncateAttributeKeys(m)
+}
+
+// mapAttributeValues replaces the tag values found in AttributeValueMap with their canonical value
+// (e.g. os=linux to os=Linux).
+func (o *otelAccumulator) mapAttributeValues(m telegraf.Metric) {
+	for key, values := range o.cfg.AttributeValueMap {
+		if value, ok := m.GetTag(key); ok {
+			if mapped, ok := values[value]; ok {
+				m.AddTag(key, mapped)
+			}
+		}
+	}
+}
+
+// hashedValueLength is the number of hex characters of the SHA-256 kept for the hashed attribute values.
+const hashedValueLength = 16
+
+// hashAttributeValues replaces the values of the tags listed in HashAttributeValues with a prefix of their
+// SHA-256, so sensitive values (e.g. user names) are not published but remain distinct and stable.
+func (o *otelAccumulator) hashAttributeValues(m telegraf.Metric) {
+	for _, key := range o.cfg.HashAttributeValues {
+		if value, ok := m.GetTag(key); ok {
+			m.AddTag(key, o.hashAttributeValue(key, value))
+		}
+	}
+}
+
+// hashAttributeValue returns the hashed value when the key is listed in HashAttributeValues, or the value as is.
+func (o *otelAccumulator) hashAttributeValue(key string, value string) string {
+	if !slices.Contains(o.cfg.HashAttributeValues, key) {
+		return value
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])[:hashedValueLength]
+}
+
+// truncateAttributeKeys shortens the tag keys longer than MaxAttributeKeyLength. The end of the kept prefix is
+// replaced by a hash of the full key, so keys sharing the same long prefix stay distinct and stable across runs.
+func (o *otelAccumulator) truncateAttributeKeys(m telegraf.Metric) {
+	if o.cfg.MaxAttributeKeyLength <= 0 {
+		return
+	}
+	for key, value := range m.Tags() {
+		if len(key) <= o.cfg.MaxAttributeKeyLength {
+			continue
+		}
+		m.RemoveTag(key)
+		m.AddTag(truncateKey(key, o.cfg.MaxAttributeKeyLength), value)
+	}
+}
+
+// truncateKey truncates the key to the max length with a _<hash> suffix.
+func truncateKey(key string, maxLength int) string {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	suffix := fmt.Sprintf("_%08x", h.Sum32())
+	if maxLength <= len(suffix) {
+		return suffix[len(suffix)-maxLength:]
+	}
+	return key[:maxLength-len(suffix)] + suffix
+}
+
+// renameAttributes renames the tag keys listed in AttributeRename (e.g. host to hostname) to align the
+// dimension names across inputs.
+func (o *otelAccumulator) renameAttributes(m telegraf.Metric) {
+	for from, to := range o.cfg.AttributeRename {
+		if value, ok := m.GetTag(from); ok && from != to {
+			m.RemoveTag(from)
+			m.AddTag(to, value)
+		}
+	}
+}
+
+// hostTag is the tag holding the name of the host, added by Telegraf to the metrics of most inputs.
+const hostTag = "host"
+
+// renameHostDimension renames the host tag to EMFHostDimension.
+func (o *otelAccumulator) renameHostDimension(m telegraf.Metric) {
+	dimension := o.cfg.EMFHostDimension
+	if dimension == "" || dimension == hostTag {
+		return
+	}
+	if value, ok := m.GetTag(hostTag); ok {
+		m.RemoveTag(hostTag)
+		m.AddTag(dimension, value)
+	}
+}
+
+// pruneConstantAttributes removes the tags equal to one of the key=value pairs in PruneConstantAttributes.
+func (o *otelAccumulator) pruneConstantAttributes(m telegraf.Metric) {
+	for _, pair := range o.cfg.PruneConstantAttributes {
+		key, constant, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		if value, ok := m.GetTag(key); ok && value == constant {
+			m.RemoveTag(key)
+		}
+	}
+}
+
+// trimTagValues removes the leading and trailing whitespace of the tag values, which would otherwise
+// create distinct but equivalent dimensions.
+func trimTagValues(m telegraf.Metric) {
+	for _, tag := range m.TagList() {
+		if trimmed := strings.TrimSpace(tag.Value); trimmed != tag.Value {
+			m.AddTag(tag.Key, trimmed)
+		}
+	}
+}
+
+// lowercaseAttributeKeys lowercases the tag keys when LowercaseAttributeKeys is enabled. Tags colliding after the
+// normalization (e.g. Host and host) are merged with the last one in key order winning, and the collision is counted.
+func (o *otelAccumulator) lowercaseAttributeKeys(m telegraf.Metric) {
+	if !o.cfg.LowercaseAttributeKeys {
+		return
+	}
+	changed := false
+	normalized := make(map[string]string, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		key := strings.ToLower(tag.Key)
+		if _, ok := normalized[key]; ok {
+			o.stats.attributeKeyCollisions.Add(1)
+		}
+		normalized[key] = tag.Value
+		changed = changed || key != tag.Key
+	}
+	if !changed {
+		return
+	}
+	for key := range m.Tags() {
+		m.RemoveTag(key)
+	}
+	for key, value := range normalized {
+		m.AddTag(key, value)
+	}
+}
+
+// validAttributeExtract returns the AttributeExtract patterns which compile, so an invalid pattern is reported
+// once when the accumulator is created instead of on every metric.
+func validAttributeExtract(patterns map[string]string, logger *zap.Logger) map[string]string {
+	var valid map[string]string
+	for tag, pattern := range patterns {
+		if _, err := compilePattern(pattern); err != nil {
+			logger.Warn("Ignored invalid attribute extract pattern", zap.String("tag", tag), zap.String("pattern", pattern), zap.Error(err))
+			continue
+		}
+		if valid == nil {
+			valid = map[string]string{}
+		}
+		valid[tag] = pattern
+	}
+	return valid
+}
+
+// extractAttributes adds a tag for every named capture group of the pattern configured for a source tag.
+// e.g. the pattern `^/dev/(?P<disk>[a-z]+)` on device=/dev/sda1 adds disk=sda. An invalid pattern only skips
+// the extraction from its source tag.
+func (o *otelAccumulator) extractAttributes(m telegraf.Metric) {
+	for tag, pattern := range o.cfg.AttributeExtract {
+		value, ok := m.GetTag(tag)
+		if !ok {
+			continue
+		}
+		re, err := compilePattern(pattern)
+		if err != nil {
+			o.logger.Debug("Ignored invalid attribute extract pattern", zap.String("tag", tag), zap.String("pattern", pattern), zap.Error(err))
+			continue
+		}
+		match := re.FindStringSubmatch(value)
+		if match == nil {
+			continue
+		}
+		for i, name := range re.SubexpNames() {
+			if name != "" && match[i] != "" {
+				m.AddTag(name, match[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/regular"
+)
+
+func TestAttributeExtract(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.AttributeExtract = map[string]string{"device": `^/dev/(?P<disk>[a-z]+)\d*$`}
+
+	acc.AddGauge("diskio", map[string]interface{}{"reads": 1}, map[string]string{"device": "/dev/sda1"}, time.Now())
+	acc.AddGauge("diskio", map[string]interface{}{"reads": 1}, map[string]string{"device": "tmpfs"}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+
+	attributes := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	disk, ok := attributes.Get("disk")
+	as.True(ok)
+	as.Equal("sda", disk.Str())
+	device, ok := attributes.Get("device")
+	as.True(ok)
+	as.Equal("/dev/sda1", device.Str())
+
+	attributes = otelMetrics.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	_, ok = attributes.Get("disk")
+	as.False(ok)
+}
+
+func TestTrimAttributeValues(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.TrimAttributeValues = true
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"region": " us-east-1 ", "az": "\tus-east-1a\n"}, time.Now())
+
+	attributes := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"region": "us-east-1", "az": "us-east-1a"}, attributes.AsRaw())
+}
+
+func TestPruneConstantAttributes(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.PruneConstantAttributes = []string{"region=us-east-1", "invalid"}
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"region": "us-east-1", "host": "a"}, time.Now())
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"region": "us-west-2", "host": "a"}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	attributes := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"host": "a"}, attributes.AsRaw())
+	attributes = otelMetrics.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"region": "us-west-2", "host": "a"}, attributes.AsRaw())
+}
+
+func TestAttributeRename(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.AttributeRename = map[string]string{"host": "hostname"}
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"host": "a", "cpu": "cpu0"}, time.Now())
+
+	attributes := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"hostname": "a", "cpu": "cpu0"}, attributes.AsRaw())
+}
+
+func TestEMFHostDimension(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.EMFHostDimension = "Host"
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"host": "a", "cpu": "cpu0"}, time.Now())
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 2.0}, map[string]string{"cpu": "cpu1"}, time.Now())
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(1)}, map[string]string{"host": "b"}, time.Now())
+
+	var attributes []map[string]any
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			forEachDataPointAttributes(ms.At(i), func(m pcommon.Map) {
+				attributes = append(attributes, m.AsRaw())
+			})
+		}
+	})
+	as.Equal([]map[string]any{{"Host": "a", "cpu": "cpu0"}, {"cpu": "cpu1"}, {"Host": "b"}}, attributes)
+}
+
+func TestMaxAttributeKeyLength(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.MaxAttributeKeyLength = 20
+
+	prefix := strings.Repeat("kubernetes_label_", 4)
+	tags := map[string]string{prefix + "app": "a", prefix + "team": "b", "host": "c"}
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, tags, time.Now())
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, tags, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	first := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes().AsRaw()
+	second := otelMetrics.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes().AsRaw()
+	as.Equal(first, second)
+	as.Len(first, 3)
+	as.Equal("c", first["host"])
+	values := map[any]string{}
+	for key, value := range first {
+		as.LessOrEqual(len(key), 20)
+		values[value] = key
+	}
+	as.True(strings.HasPrefix(values["a"], "kubernetes_"))
+	as.NotEqual(values["a"], values["b"])
+	as.Equal(truncateKey(prefix+"app", 20), values["a"])
+	as.Len(truncateKey(prefix+"app", 4), 4)
+}
+
+func TestInvalidAttributeExtract(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.AttributeExtract = map[string]string{
+		"device": `^/dev/(?P<disk>[a-z]+)\d*$`,
+		"path":   `^(?P<mount>/[a-z]+`,
+	}
+	acc.cfg.HashAttributeValues = []string{"user"}
+
+	tags := map[string]string{"device": "/dev/sda1", "path": "/home/jdoe", "user": "jdoe"}
+	acc.AddGauge("diskio", map[string]interface{}{"reads": 1}, tags, time.Now())
+
+	attributes := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"device": "/dev/sda1", "disk": "sda", "path": "/home/jdoe", "user": "d30a5f57532a6036"}, attributes.AsRaw())
+
+	patterns := map[string]string{"device": `^/dev/(?P<disk>[a-z]+)`, "path": `^(?P<mount>/[a-z]+`}
+	as.Equal(map[string]string{"device": `^/dev/(?P<disk>[a-z]+)`}, validAttributeExtract(patterns, zap.NewNop()))
+	as.Len(patterns, 2)
+	as.Nil(validAttributeExtract(nil, zap.NewNop()))
+}
+
+func TestHashAttributeValues(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.HashAttributeValues = []string{"user", "client_ip"}
+
+	tags := map[string]string{"user": "jdoe", "host": "a"}
+	acc.AddGauge("sessions", map[string]interface{}{"active": 1}, tags, time.Now())
+	acc.AddGauge("sessions", map[string]interface{}{"active": 1}, tags, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(2, otelMetrics.ResourceMetrics().Len())
+	for i := 0; i < otelMetrics.ResourceMetrics().Len(); i++ {
+		attributes := otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+		as.Equal(map[string]any{"user": "d30a5f57532a6036", "host": "a"}, attributes.AsRaw())
+	}
+	as.Equal("jdoe", tags["user"])
+}
+
+func TestHashAttributeValuesOfStringFields(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.StringFieldsAsAttributes = true
+	acc.cfg.HashAttributeValues = []string{"user"}
+	acc.cfg.AttributeExtract = map[string]string{"path": `^(?P<mount>/[a-z]+`}
+
+	fields := map[string]interface{}{"active": 1, "user": "jdoe", "state": "idle"}
+	acc.AddGauge("sessions", fields, map[string]string{"path": "/home"}, time.Now())
+
+	attributes := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"user": "d30a5f57532a6036", "state": "idle", "path": "/home"}, attributes.AsRaw())
+}
+
+func TestAttributeValueMap(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.AttributeValueMap = map[string]map[string]string{"os": {"linux": "Linux", "windows": "Windows"}}
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"os": "linux", "host": "linux"}, time.Now())
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"os": "darwin"}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	attributes := otelMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"os": "Linux", "host": "linux"}, attributes.AsRaw())
+	attributes = otelMetrics.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"os": "darwin"}, attributes.AsRaw())
+}
+
+func TestProcessAttributes(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.ProcessAttributes = map[string]string{"agent_id": "agent-1", "host": "process"}
+
+	dist := regular.NewRegularDistribution()
+	as.NoError(dist.AddEntry(1, 1))
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"host": "a"}, time.Now())
+	acc.AddCounter("net", map[string]interface{}{"bytes_recv": int64(1)}, map[string]string{}, time.Now())
+	acc.AddHistogram("latency", map[string]interface{}{"value": dist}, map[string]string{}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(3, otelMetrics.ResourceMetrics().Len())
+	for i := 0; i < otelMetrics.ResourceMetrics().Len(); i++ {
+		m := otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics().At(0)
+		var attributes pcommon.Map
+		switch m.Type() {
+		case pmetric.MetricTypeGauge:
+			attributes = m.Gauge().DataPoints().At(0).Attributes()
+			as.Equal(map[string]any{"agent_id": "agent-1", "host": "a"}, attributes.AsRaw())
+			continue
+		case pmetric.MetricTypeSum:
+			attributes = m.Sum().DataPoints().At(0).Attributes()
+		case pmetric.MetricTypeHistogram:
+			attributes = m.Histogram().DataPoints().At(0).Attributes()
+		}
+		as.Equal(map[string]any{"agent_id": "agent-1", "host": "process"}, attributes.AsRaw())
+	}
+}
+
+func TestLowercaseAttributeKeys(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.LowercaseAttributeKeys = true
+
+	acc.AddGauge("cpu", map[string]interface{}{"usage_idle": 1.0}, map[string]string{"Host": "a", "host": "b", "Region": "us-east-1"}, time.Now())
+
+	attributes := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Attributes()
+	as.Equal(map[string]any{"host": "b", "region": "us-east-1"}, attributes.AsRaw())
+	as.EqualValues(1, acc.AttributeKeyCollisions())
+}
@@ -0,0 +1,25 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// MetricTransformer transforms a Telegraf metric before it is converted. It returns the transformed metric, which
+// can be the mutated metric or a new one, and false to drop the metric.
+type MetricTransformer interface {
+	Transform(telegraf.Metric) (telegraf.Metric, bool)
+}
+
+// transform runs the metric through the Transformers in order. It returns nil as soon as a transformer drops it.
+func (o *otelAccumulator) transform(m telegraf.Metric) telegraf.Metric {
+	for _, transformer := range o.cfg.Transformers {
+		var ok bool
+		if m, ok = transformer.Transform(m); !ok || m == nil {
+			return nil
+		}
+	}
+	return m
+}
@@ -0,0 +1,60 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/metric"
+)
+
+type transformerFunc func(telegraf.Metric) (telegraf.Metric, bool)
+
+func (f transformerFunc) Transform(m telegraf.Metric) (telegraf.Metric, bool) {
+	return f(m)
+}
+
+func TestTransformers(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+
+	var calls []string
+	rename := transformerFunc(func(m telegraf.Metric) (telegraf.Metric, bool) {
+		calls = append(calls, "rename:"+m.Name())
+		m.SetName("memory")
+		return m, true
+	})
+	scale := transformerFunc(func(m telegraf.Metric) (telegraf.Metric, bool) {
+		calls = append(calls, "scale:"+m.Name())
+		if m.Name() != "memory" {
+			return m, false
+		}
+		used, _ := m.GetField("used")
+		m.AddField("used", used.(float64)/1024)
+		return m, true
+	})
+	acc.cfg.Transformers = []MetricTransformer{rename, scale}
+
+	acc.AddGauge("mem", map[string]interface{}{"used": 2048.0}, map[string]string{}, time.Now())
+	metrics := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	as.Equal(1, metrics.Len())
+	as.Equal(metric.DecorateMetricName("memory", "used"), metrics.At(0).Name())
+	as.Equal(2.0, metrics.At(0).Gauge().DataPoints().At(0).DoubleValue())
+	as.Equal([]string{"rename:mem", "scale:memory"}, calls)
+
+	// a drop short-circuits the following transformers
+	calls = nil
+	drop := transformerFunc(func(m telegraf.Metric) (telegraf.Metric, bool) {
+		calls = append(calls, "drop:"+m.Name())
+		return nil, false
+	})
+	acc.cfg.Transformers = []MetricTransformer{drop, rename, scale}
+	acc.AddGauge("mem", map[string]interface{}{"used": 2048.0}, map[string]string{}, time.Now())
+	as.Equal(0, acc.GetOtelMetrics().ResourceMetrics().Len())
+	as.Equal([]string{"drop:mem"}, calls)
+}
@@ -0,0 +1,109 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"math"
+	"slices"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// resolveType returns the metric with the value type decided by the type options from the Config.
+// Telegraf metrics cannot change their type, so a copy is created when the type changes.
+func (o *otelAccumulator) resolveType(m telegraf.Metric) telegraf.Metric {
+	tp := m.Type()
+	if tp == telegraf.Untyped && o.cfg.TypeResolver != nil {
+		if resolved := o.cfg.TypeResolver(m.Name(), m.Tags()); isNumberType(resolved) {
+			tp = resolved
+		}
+	}
+	if overridden, ok := o.typeOverrideByTag(m); ok && isNumberType(tp) {
+		tp = overridden
+	}
+	if tp == m.Type() {
+		return m
+	}
+	return metric.New(m.Name(), m.Tags(), m.Fields(), m.Time(), tp)
+}
+
+// typeOverrideByTag returns the value type configured in TypeOverrideByTag for one of the tags of the metric. The
+// tag keys are checked in order so the override is deterministic when several tags match.
+func (o *otelAccumulator) typeOverrideByTag(m telegraf.Metric) (telegraf.ValueType, bool) {
+	if len(o.cfg.TypeOverrideByTag) == 0 {
+		return telegraf.Untyped, false
+	}
+	for _, tag := range m.TagList() {
+		if tp, ok := o.cfg.TypeOverrideByTag[tag.Key][tag.Value]; ok && isNumberType(tp) {
+			return tp, true
+		}
+	}
+	return telegraf.Untyped, false
+}
+
+// isNumberType checks if the value type is converted to number data points.
+func isNumberType(tp telegraf.ValueType) bool {
+	return tp == telegraf.Counter || tp == telegraf.Gauge || tp == telegraf.Untyped
+}
+
+// stabilizeNumericType converts the numeric values of a metric to the type of its first value, so the type of its
+// data points does not flap between int and double. The doubles which do not fit in an int64 are kept as doubles.
+func (o *otelAccumulator) stabilizeNumericType(m telegraf.Metric, field string, otelValue interface{}) interface{} {
+	if !o.cfg.StableNumericType {
+		return otelValue
+	}
+	var valueType pmetric.NumberDataPointValueType
+	switch otelValue.(type) {
+	case float64:
+		valueType = pmetric.NumberDataPointValueTypeDouble
+	case int64:
+		valueType = pmetric.NumberDataPointValueTypeInt
+	default:
+		return otelValue
+	}
+	name := metricName(&o.cfg, m.Name(), field)
+
+	o.mutex.Lock()
+	first, ok := o.numericTypes.get(name)
+	if !ok {
+		o.numericTypes.put(name, valueType, o.cfg.MaxStableNumericTypeMetrics)
+	}
+	o.mutex.Unlock()
+	if !ok || first == valueType {
+		return otelValue
+	}
+	switch v := otelValue.(type) {
+	case float64:
+		if rounded, ok := roundToInt64(v); ok {
+			return rounded
+		}
+	case int64:
+		return float64(v)
+	}
+	return otelValue
+}
+
+// forceIntType rounds the double values of the metrics listed in IntMetricNames to integers. The values which do
+// not fit in an int64 are kept as doubles.
+func (o *otelAccumulator) forceIntType(m telegraf.Metric, field string, otelValue interface{}) interface{} {
+	v, ok := otelValue.(float64)
+	if !ok || len(o.cfg.IntMetricNames) == 0 || !slices.Contains(o.cfg.IntMetricNames, metricName(&o.cfg, m.Name(), field)) {
+		return otelValue
+	}
+	if rounded, ok := roundToInt64(v); ok {
+		return rounded
+	}
+	return otelValue
+}
+
+// roundToInt64 rounds the value to the nearest integer, or returns false if it does not fit in an int64.
+func roundToInt64(v float64) (int64, bool) {
+	rounded := math.Round(v)
+	if rounded < math.MinInt64 || rounded >= math.MaxInt64 || math.IsNaN(rounded) {
+		return 0, false
+	}
+	return int64(rounded), true
+}
@@ -0,0 +1,213 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package accumulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/metric"
+)
+
+func TestTypeResolver(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.TypeResolver = func(_ string, tags map[string]string) telegraf.ValueType {
+		switch tags["kind"] {
+		case "counter":
+			return telegraf.Counter
+		case "histogram":
+			return telegraf.Histogram
+		}
+		return telegraf.Untyped
+	}
+
+	now := time.Now()
+	acc.AddFields("requests", map[string]interface{}{"value": 1}, map[string]string{"kind": "counter"}, now)
+	acc.AddMetric(testutil.MustMetric("requests", map[string]string{"kind": "counter"}, map[string]interface{}{"value": 2}, now))
+	acc.AddFields("requests", map[string]interface{}{"value": 3}, map[string]string{"kind": "histogram"}, now)
+	acc.AddFields("requests", map[string]interface{}{"value": 4}, map[string]string{}, now)
+	// only untyped metrics are resolved
+	acc.AddGauge("requests", map[string]interface{}{"value": 5}, map[string]string{"kind": "counter"}, now)
+
+	otelMetrics := acc.GetOtelMetrics()
+	as.Equal(5, otelMetrics.ResourceMetrics().Len())
+	wantTypes := []pmetric.MetricType{
+		pmetric.MetricTypeSum,
+		pmetric.MetricTypeSum,
+		pmetric.MetricTypeGauge,
+		pmetric.MetricTypeGauge,
+		pmetric.MetricTypeGauge,
+	}
+	for i, want := range wantTypes {
+		as.Equal(want, otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics().At(0).Type(), i)
+	}
+}
+
+func TestStableNumericType(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.StableNumericType = true
+
+	valueTypes := func() []pmetric.NumberDataPointValueType {
+		var types []pmetric.NumberDataPointValueType
+		otelMetrics := acc.GetOtelMetrics()
+		for i := 0; i < otelMetrics.ResourceMetrics().Len(); i++ {
+			metrics := otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics()
+			for j := 0; j < metrics.Len(); j++ {
+				types = append(types, metrics.At(j).Gauge().DataPoints().At(0).ValueType())
+			}
+		}
+		return types
+	}
+
+	acc.AddGauge("queue", map[string]interface{}{"depth": int64(1)}, map[string]string{}, time.Now())
+	as.Equal([]pmetric.NumberDataPointValueType{pmetric.NumberDataPointValueTypeInt}, valueTypes())
+
+	// depth was first seen as an int, latency as a double
+	acc.AddGauge("queue", map[string]interface{}{"depth": 1.5}, map[string]string{}, time.Now())
+	acc.AddGauge("queue", map[string]interface{}{"latency": 0.5}, map[string]string{}, time.Now())
+	acc.AddGauge("queue", map[string]interface{}{"latency": int64(2)}, map[string]string{}, time.Now())
+	as.Equal([]pmetric.NumberDataPointValueType{
+		pmetric.NumberDataPointValueTypeInt,
+		pmetric.NumberDataPointValueTypeDouble,
+		pmetric.NumberDataPointValueTypeDouble,
+	}, valueTypes())
+
+	// a double beyond the int64 range is kept as is
+	acc.AddGauge("queue", map[string]interface{}{"depth": 1e30}, map[string]string{}, time.Now())
+	as.Equal([]pmetric.NumberDataPointValueType{pmetric.NumberDataPointValueTypeDouble}, valueTypes())
+}
+
+func TestStableNumericTypeBounded(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.StableNumericType = true
+	acc.cfg.MaxStableNumericTypeMetrics = 1
+
+	acc.AddGauge("queue", map[string]interface{}{"depth": int64(1)}, map[string]string{}, time.Now())
+	acc.AddGauge("queue", map[string]interface{}{"latency": 0.5}, map[string]string{}, time.Now())
+	as.Equal(1, acc.numericTypes.len())
+	acc.GetOtelMetrics()
+
+	// depth was evicted, so its type is the one seen next
+	acc.AddGauge("queue", map[string]interface{}{"depth": 1.5}, map[string]string{}, time.Now())
+	dp := acc.GetOtelMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+	as.Equal(1.5, dp.DoubleValue())
+}
+
+func TestIntMetricNames(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.StableNumericType = true
+	acc.cfg.IntMetricNames = []string{metric.DecorateMetricName("disk", "used_percent"), metric.DecorateMetricName("disk", "huge")}
+
+	fields := map[string]interface{}{"used_percent": 72.6, "free_percent": 27.4, "huge": 1e300}
+	acc.AddGauge("disk", fields, map[string]string{}, time.Now())
+
+	values := map[string]interface{}{}
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			dp := ms.At(i).Gauge().DataPoints().At(0)
+			if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+				values[ms.At(i).Name()] = dp.IntValue()
+			} else {
+				values[ms.At(i).Name()] = dp.DoubleValue()
+			}
+		}
+	})
+	as.Equal(map[string]interface{}{
+		metric.DecorateMetricName("disk", "used_percent"): int64(73),
+		metric.DecorateMetricName("disk", "free_percent"): 27.4,
+		metric.DecorateMetricName("disk", "huge"):         1e300,
+	}, values)
+}
+
+func TestTypeOverrideByTag(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.TypeOverrideByTag = map[string]map[string]telegraf.ValueType{
+		"role": {"counter": telegraf.Counter},
+	}
+
+	acc.AddGauge("requests", map[string]interface{}{"total": int64(10)}, map[string]string{"role": "counter"}, time.Now())
+	acc.AddGauge("requests", map[string]interface{}{"total": int64(10)}, map[string]string{"role": "gauge"}, time.Now())
+	acc.AddFields("requests", map[string]interface{}{"total": int64(10)}, map[string]string{"role": "counter"}, time.Now())
+	acc.AddGauge("requests", map[string]interface{}{"total": int64(10)}, map[string]string{}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	var types []pmetric.MetricType
+	for i := 0; i < otelMetrics.ResourceMetrics().Len(); i++ {
+		types = append(types, otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics().At(0).Type())
+	}
+	as.Equal([]pmetric.MetricType{
+		pmetric.MetricTypeSum,
+		pmetric.MetricTypeGauge,
+		pmetric.MetricTypeSum,
+		pmetric.MetricTypeGauge,
+	}, types)
+}
+
+func TestForceGaugeNames(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.ForceGaugeNames = []string{metric.DecorateMetricName("queue", "depth")}
+	acc.cfg.TypeOverrideByTag = map[string]map[string]telegraf.ValueType{
+		"role": {"counter": telegraf.Counter},
+	}
+
+	acc.AddFields("queue", map[string]interface{}{"depth": int64(5), "enqueued": int64(10)}, map[string]string{"role": "counter"}, time.Now())
+	acc.AddCounter("queue", map[string]interface{}{"depth": int64(5)}, map[string]string{}, time.Now())
+
+	otelMetrics := acc.GetOtelMetrics()
+	types := map[string][]pmetric.MetricType{}
+	for i := 0; i < otelMetrics.ResourceMetrics().Len(); i++ {
+		metrics := otelMetrics.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics()
+		for j := 0; j < metrics.Len(); j++ {
+			types[metrics.At(j).Name()] = append(types[metrics.At(j).Name()], metrics.At(j).Type())
+		}
+	}
+	as.Equal(map[string][]pmetric.MetricType{
+		metric.DecorateMetricName("queue", "depth"):    {pmetric.MetricTypeGauge, pmetric.MetricTypeGauge},
+		metric.DecorateMetricName("queue", "enqueued"): {pmetric.MetricTypeSum},
+	}, types)
+}
+
+func TestInferSumFromName(t *testing.T) {
+	as := assert.New(t)
+	acc := newOtelAccumulatorWithTestRunningInputs(as, nil, false)
+	acc.cfg.InferSumFromName = true
+	acc.cfg.ForceGaugeNames = []string{metric.DecorateMetricName("http", "inflight_total")}
+	acc.cfg.TypeOverrideByTag = map[string]map[string]telegraf.ValueType{
+		"role": {"gauge": telegraf.Gauge},
+	}
+
+	fields := map[string]interface{}{"requests_total": int64(10), "requests_per_second": 2.5, "inflight_total": int64(3)}
+	acc.AddFields("http", fields, map[string]string{}, time.Now())
+	// the overridden type is kept
+	acc.AddFields("grpc", map[string]interface{}{"requests_total": int64(10)}, map[string]string{"role": "gauge"}, time.Now())
+	acc.AddGauge("tcp", map[string]interface{}{"resets_total": int64(1)}, map[string]string{}, time.Now())
+
+	types := map[string]pmetric.MetricType{}
+	forEachMetricSlice(acc.GetOtelMetrics(), func(ms pmetric.MetricSlice) {
+		for i := 0; i < ms.Len(); i++ {
+			types[ms.At(i).Name()] = ms.At(i).Type()
+			if ms.At(i).Type() == pmetric.MetricTypeSum {
+				as.True(ms.At(i).Sum().IsMonotonic())
+			}
+		}
+	})
+	as.Equal(map[string]pmetric.MetricType{
+		metric.DecorateMetricName("http", "requests_total"):      pmetric.MetricTypeSum,
+		metric.DecorateMetricName("http", "requests_per_second"): pmetric.MetricTypeGauge,
+		metric.DecorateMetricName("http", "inflight_total"):      pmetric.MetricTypeGauge,
+		metric.DecorateMetricName("grpc", "requests_total"):      pmetric.MetricTypeGauge,
+		metric.DecorateMetricName("tcp", "resets_total"):         pmetric.MetricTypeGauge,
+	}, types)
+}
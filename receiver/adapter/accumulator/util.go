@@ -4,12 +4,117 @@
 package accumulator
 
 import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gobwas/glob"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 )
 
+// compiledPatterns and compiledGlobs cache the compiled regular expressions and globs from the Config
+// since the patterns are evaluated for every metric.
+var (
+	compiledPatterns sync.Map
+	compiledGlobs    sync.Map
+)
+
 // Otel Attributes = Telegraf Tags = CloudWatch Dimensions
-func addTagsToAttributes(attributes pcommon.Map, tags map[string]string) {
+func addTagsToAttributes(cfg *Config, attributes pcommon.Map, tags map[string]string) {
+	attributes.EnsureCapacity(len(tags) + len(cfg.ProcessAttributes))
 	for tag, value := range tags {
+		if cfg.InternAttributes {
+			tag, value = attributeInterner.intern(tag), attributeInterner.intern(value)
+		}
 		attributes.PutStr(tag, value)
 	}
+	// The tags of the metric take precedence over the process attributes
+	for key, value := range cfg.ProcessAttributes {
+		if _, ok := attributes.Get(key); !ok {
+			attributes.PutStr(key, value)
+		}
+	}
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := compiledPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(pattern, re)
+	return re, nil
+}
+
+func compileGlob(pattern string) (glob.Glob, error) {
+	if g, ok := compiledGlobs.Load(pattern); ok {
+		return g.(glob.Glob), nil
+	}
+	g, err := glob.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	compiledGlobs.Store(pattern, g)
+	return g, nil
+}
+
+// seriesKey identifies a time series by its metric name and tags regardless of the tag order.
+func seriesKey(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, k := range keys {
+		sb.WriteByte(0)
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(tags[k])
+	}
+	return sb.String()
+}
+
+func isNegative(value interface{}) bool {
+	switch v := value.(type) {
+	case int64:
+		return v < 0
+	case float64:
+		return v < 0
+	}
+	return false
+}
+
+// zeroOf returns zero with the same type as the OTEL value.
+func zeroOf(value interface{}) interface{} {
+	if _, ok := value.(int64); ok {
+		return int64(0)
+	}
+	return float64(0)
+}
+
+// toFloat64 converts a numeric field value to float64.
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	}
+	return 0, false
 }
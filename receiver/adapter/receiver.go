@@ -44,6 +44,7 @@ func (r *AdaptedReceiver) start(_ context.Context, _ component.Host) error {
 	// https://github.com/influxdata/telegraf/blob/3b3584b40b7c9ea10ae9cb02137fc072da202704/agent/agent.go#L316-L317
 
 	r.accumulator = accumulator.NewAccumulator(r.input, r.ctx, r.consumer, r.logger)
+	r.accumulator.SetScope(r.input.Config.Name, "")
 
 	// Service Input differs from a regular plugin in that it operates a background service while Telegraf/CWAgent is running
 	// https://github.com/influxdata/telegraf/blob/d67f75e55765d364ad0aabe99382656cb5b51014/docs/INPUTS.md#service-input-plugins